@@ -0,0 +1,151 @@
+package leadsdb
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Circuit breaker defaults used for zero CircuitBreakerConfig fields.
+const (
+	DefaultBreakerFailureRatio = 0.5
+	DefaultBreakerMinRequests  = 10
+	DefaultBreakerWindow       = 30 * time.Second
+	DefaultBreakerOpenTimeout  = 30 * time.Second
+)
+
+// CircuitBreakerConfig configures the circuit breaker enabled by WithCircuitBreaker.
+// Zero fields use the defaults.
+type CircuitBreakerConfig struct {
+	// FailureRatio is the share of failed requests, between 0 and 1, that opens the circuit.
+	FailureRatio float64
+	// MinRequests is the number of requests within Window needed before the circuit can open.
+	MinRequests int
+	// Window is the period over which requests are counted.
+	Window time.Duration
+	// OpenTimeout is how long the circuit stays open before a probe request is let through.
+	OpenTimeout time.Duration
+}
+
+// WithCircuitBreaker enables a circuit breaker shared by all calls made through the client.
+//
+// Network errors and 5xx responses count as failures. Once the failure ratio
+// within the window is reached, the circuit opens and requests fail immediately
+// with an error matching ErrCircuitOpen, without being retried. After
+// OpenTimeout a single probe request is let through: if it succeeds the circuit
+// closes, otherwise it stays open for another OpenTimeout.
+func WithCircuitBreaker(cfg CircuitBreakerConfig) Option {
+	return func(c *Client) {
+		c.breaker = newBreaker(cfg)
+	}
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type breaker struct {
+	cfg CircuitBreakerConfig
+
+	mu          sync.Mutex
+	state       breakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+func newBreaker(cfg CircuitBreakerConfig) *breaker {
+	if cfg.FailureRatio <= 0 {
+		cfg.FailureRatio = DefaultBreakerFailureRatio
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = DefaultBreakerMinRequests
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultBreakerWindow
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = DefaultBreakerOpenTimeout
+	}
+
+	return &breaker{cfg: cfg, windowStart: time.Now()}
+}
+
+// allow reports whether a request may be sent. Every allowed request must be
+// followed by a call to done.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cfg.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// done records the outcome of a request let through by allow. Requests
+// abandoned by the caller are not counted.
+func (b *breaker) done(failed, abandoned bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+
+	if b.state == breakerHalfOpen {
+		b.probing = false
+		switch {
+		case abandoned:
+		case failed:
+			b.state = breakerOpen
+			b.openedAt = now
+		default:
+			b.state = breakerClosed
+			b.windowStart = now
+			b.requests, b.failures = 0, 0
+		}
+		return
+	}
+
+	if b.state != breakerClosed || abandoned {
+		return
+	}
+
+	if now.Sub(b.windowStart) >= b.cfg.Window {
+		b.windowStart = now
+		b.requests, b.failures = 0, 0
+	}
+
+	b.requests++
+	if failed {
+		b.failures++
+	}
+
+	if b.requests >= b.cfg.MinRequests && float64(b.failures)/float64(b.requests) >= b.cfg.FailureRatio {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+}
+
+// breakerFailure reports whether a response status counts as a failure.
+func breakerFailure(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,147 @@
+package leadsdb_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestBreakerOpeningKeepsCause(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	client := srv.Client(
+		leadsdb.WithRetryPolicy(leadsdbtest.NoDelay{}),
+		leadsdb.WithCircuitBreaker(leadsdb.CircuitBreakerConfig{
+			FailureRatio: 0.5,
+			MinRequests:  1,
+			Window:       time.Minute,
+			OpenTimeout:  time.Minute,
+		}),
+	)
+
+	srv.FailNext(http.StatusServiceUnavailable, 1)
+	_, err := client.List(context.Background())
+
+	if !errors.Is(err, leadsdb.ErrCircuitOpen) {
+		t.Errorf("got %v, want it to match ErrCircuitOpen", err)
+	}
+	var apiErr *leadsdb.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want it to carry the 503 that opened the breaker", err)
+	}
+
+	_, err = client.List(context.Background())
+	if err != leadsdb.ErrCircuitOpen {
+		t.Errorf("got %v, want a bare ErrCircuitOpen for a call that made no attempt", err)
+	}
+}
+
+func TestBreakerStates(t *testing.T) {
+	const openTimeout = 50 * time.Millisecond
+
+	newClient := func(srv *leadsdbtest.Server) *leadsdb.Client {
+		return srv.Client(
+			leadsdb.WithMaxRetries(1),
+			leadsdb.WithCircuitBreaker(leadsdb.CircuitBreakerConfig{
+				FailureRatio: 0.5,
+				MinRequests:  1,
+				Window:       time.Minute,
+				OpenTimeout:  openTimeout,
+			}),
+		)
+	}
+	// open fails one request so the breaker opens, and waits until it lets a
+	// probe through.
+	open := func(t *testing.T, srv *leadsdbtest.Server, client *leadsdb.Client) {
+		t.Helper()
+		srv.FailNext(http.StatusServiceUnavailable, 1)
+		if _, err := client.List(context.Background()); err == nil {
+			t.Fatal("List succeeded, want a 503")
+		}
+		if _, err := client.List(context.Background()); err != leadsdb.ErrCircuitOpen {
+			t.Fatalf("got %v, want the breaker to open", err)
+		}
+		time.Sleep(openTimeout + 10*time.Millisecond)
+	}
+
+	t.Run("half-open allows one probe and closes on success", func(t *testing.T) {
+		srv := leadsdbtest.NewServer()
+		defer srv.Close()
+		client := newClient(srv)
+		ctx := context.Background()
+
+		open(t, srv, client)
+
+		srv.InjectFault(leadsdbtest.Fault{Delay: 200 * time.Millisecond})
+		probe := make(chan error, 1)
+		go func() {
+			_, err := client.List(ctx)
+			probe <- err
+		}()
+		for len(srv.Requests()) < 2 {
+			time.Sleep(time.Millisecond)
+		}
+
+		if _, err := client.List(ctx); err != leadsdb.ErrCircuitOpen {
+			t.Fatalf("got %v during the probe, want ErrCircuitOpen", err)
+		}
+		if err := <-probe; err != nil {
+			t.Fatalf("probe: %v", err)
+		}
+		if got := len(srv.Requests()); got != 2 {
+			t.Fatalf("server received %d requests, want 2", got)
+		}
+
+		for range 3 {
+			if _, err := client.List(ctx); err != nil {
+				t.Fatalf("got %v after a successful probe, want the breaker closed", err)
+			}
+		}
+	})
+
+	t.Run("failed probe reopens", func(t *testing.T) {
+		srv := leadsdbtest.NewServer()
+		defer srv.Close()
+		client := newClient(srv)
+		ctx := context.Background()
+
+		open(t, srv, client)
+
+		srv.FailNext(http.StatusServiceUnavailable, 1)
+		var apiErr *leadsdb.APIError
+		if _, err := client.List(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("got %v, want the probe to fail with 503", err)
+		}
+		if _, err := client.List(ctx); err != leadsdb.ErrCircuitOpen {
+			t.Fatalf("got %v after a failed probe, want ErrCircuitOpen", err)
+		}
+		if got := len(srv.Requests()); got != 2 {
+			t.Fatalf("server received %d requests, want 2", got)
+		}
+	})
+
+	t.Run("4xx is not a failure", func(t *testing.T) {
+		srv := leadsdbtest.NewServer()
+		defer srv.Close()
+		client := newClient(srv)
+		ctx := context.Background()
+
+		for range 5 {
+			if _, err := client.Get(ctx, "missing"); !errors.Is(err, leadsdb.ErrNotFound) {
+				t.Fatalf("got %v, want ErrNotFound", err)
+			}
+		}
+		if _, err := client.List(ctx); err != nil {
+			t.Fatalf("got %v, want the breaker closed after 404s", err)
+		}
+		if got := len(srv.Requests()); got != 6 {
+			t.Fatalf("server received %d requests, want 6", got)
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package leadsdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// LeadPatch is the update of a single lead in a bulk update.
+type LeadPatch struct {
+	// ID is the ID of the lead to update.
+	ID string `json:"id"`
+	// Input holds the changes, as for Update.
+	Input *UpdateLeadInput `json:"input"`
+}
+
+// BulkUpdateResult contains the result of a bulk update operation.
+type BulkUpdateResult struct {
+	Total   int               `json:"total"`
+	Success int               `json:"success"`
+	Failed  int               `json:"failed"`
+	Updated []BulkUpdatedLead `json:"updated"`
+	Errors  []BulkUpdateError `json:"errors"`
+}
+
+// BulkUpdatedLead contains the result of a successfully updated lead.
+type BulkUpdatedLead struct {
+	Index     int      `json:"index"`
+	ID        string   `json:"id"`
+	UpdatedAt UnixTime `json:"updated_at"`
+
+	// Patch and Batch are filled in by BulkUpdateFromChan.
+	// Patch is the submitted patch and Batch the zero-based number of the request it was sent in.
+	Patch *LeadPatch `json:"-"`
+	Batch int        `json:"-"`
+}
+
+// BulkUpdateError contains the error for a failed lead update.
+type BulkUpdateError struct {
+	Index   int    `json:"index"`
+	ID      string `json:"id"`
+	Message string `json:"message"`
+
+	// Patch and Batch are filled in by BulkUpdateFromChan.
+	// Patch is the submitted patch and Batch the zero-based number of the request it was sent in.
+	Patch *LeadPatch `json:"-"`
+	Batch int        `json:"-"`
+	// Err is the cause when the whole batch failed, rather than the API rejecting this update.
+	Err error `json:"-"`
+}
+
+// Error implements the error interface.
+func (e *BulkUpdateError) Error() string {
+	return fmt.Sprintf("leadsdb: update at index %d: %s", e.Index, strings.TrimPrefix(e.Message, "leadsdb: "))
+}
+
+// Unwrap returns the batch error, if any.
+func (e *BulkUpdateError) Unwrap() error {
+	return e.Err
+}
+
+// BulkDeleteResult contains the result of a bulk delete operation.
+type BulkDeleteResult struct {
+	Total   int               `json:"total"`
+	Success int               `json:"success"`
+	Failed  int               `json:"failed"`
+	Deleted []BulkDeletedLead `json:"deleted"`
+	Errors  []BulkDeleteError `json:"errors"`
+}
+
+// BulkDeletedLead contains the result of a successfully deleted lead.
+type BulkDeletedLead struct {
+	Index int    `json:"index"`
+	ID    string `json:"id"`
+
+	// Batch is filled in by BulkDeleteFromChan. It is the zero-based number of
+	// the request the ID was sent in.
+	Batch int `json:"-"`
+}
+
+// BulkDeleteError contains the error for a failed lead deletion.
+type BulkDeleteError struct {
+	Index   int    `json:"index"`
+	ID      string `json:"id"`
+	Message string `json:"message"`
+
+	// Batch is filled in by BulkDeleteFromChan. It is the zero-based number of
+	// the request the ID was sent in.
+	Batch int `json:"-"`
+	// Err is the cause when the whole batch failed, rather than the API rejecting this deletion.
+	Err error `json:"-"`
+}
+
+// Error implements the error interface.
+func (e *BulkDeleteError) Error() string {
+	return fmt.Sprintf("leadsdb: delete at index %d: %s", e.Index, strings.TrimPrefix(e.Message, "leadsdb: "))
+}
+
+// Unwrap returns the batch error, if any.
+func (e *BulkDeleteError) Unwrap() error {
+	return e.Err
+}
+
+// BulkUpdate updates up to 100 leads in a single request. Leads that do not
+// exist or whose update is rejected are reported in Errors.
+func (c *Client) BulkUpdate(ctx context.Context, patches []LeadPatch) (*BulkUpdateResult, error) {
+	if len(patches) == 0 {
+		return nil, errors.New("leadsdb: patches is required")
+	}
+	if len(patches) > maxBatchSize {
+		return nil, errors.New("leadsdb: maximum 100 patches allowed")
+	}
+	for i := range patches {
+		if msg := validatePatch(&patches[i]); msg != "" {
+			return nil, fmt.Errorf("leadsdb: patch at index %d: %s", i, msg)
+		}
+	}
+
+	return c.bulkUpdate(ctx, patches)
+}
+
+// bulkUpdate sends patches, which have already been validated, in one request.
+func (c *Client) bulkUpdate(ctx context.Context, patches []LeadPatch) (*BulkUpdateResult, error) {
+	body := struct {
+		Updates []LeadPatch `json:"updates"`
+	}{Updates: patches}
+
+	var result BulkUpdateResult
+	if err := c.do(ctx, http.MethodPatch, "/leads/batch", body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// BulkDelete deletes up to 100 leads in a single request. IDs of leads that do
+// not exist are reported in Errors.
+//
+// The IDs are sent with POST /leads/batch/delete rather than as the body of a
+// DELETE request, which proxies may drop. Like other POST requests it carries
+// an Idempotency-Key, so a retried request reports the original outcome.
+func (c *Client) BulkDelete(ctx context.Context, ids []string) (*BulkDeleteResult, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("leadsdb: ids is required")
+	}
+	if len(ids) > maxBatchSize {
+		return nil, errors.New("leadsdb: maximum 100 ids allowed")
+	}
+	for i, id := range ids {
+		if id == "" {
+			return nil, fmt.Errorf("leadsdb: id at index %d is empty", i)
+		}
+	}
+
+	body := struct {
+		IDs []string `json:"ids"`
+	}{IDs: ids}
+
+	var result BulkDeleteResult
+	if err := c.do(ctx, http.MethodPost, "/leads/batch/delete", body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// BulkUpdateFromChan reads patches from the input channel and applies them in
+// batches of 100, like BulkCreateFromChan. Every patch that is not applied
+// produces a *BulkUpdateError on the error channel carrying the original patch,
+// including invalid patches and patches in a batch whose request failed.
+// Indices in results and errors are positions in the input stream.
+func (c *Client) BulkUpdateFromChan(ctx context.Context, patches <-chan LeadPatch, opts ...BulkChanOption) (<-chan *BulkUpdatedLead, <-chan error) {
+	return streamBatches(ctx, patches, bulkChanConfig(opts), c.updateBatch)
+}
+
+// BulkDeleteFromChan reads lead IDs from the input channel and deletes them in
+// batches of 100, like BulkCreateFromChan. Every ID that is not deleted
+// produces a *BulkDeleteError on the error channel, including empty IDs and
+// IDs in a batch whose request failed. Indices in results and errors are
+// positions in the input stream.
+func (c *Client) BulkDeleteFromChan(ctx context.Context, ids <-chan string, opts ...BulkChanOption) (<-chan *BulkDeletedLead, <-chan error) {
+	return streamBatches(ctx, ids, bulkChanConfig(opts), c.deleteBatch)
+}
+
+// updateBatch applies patches in one request and returns per-patch outcomes.
+// Indices are offset by offset, and patches that fail validation or belong to
+// a failed request are reported as errors instead of being dropped.
+func (c *Client) updateBatch(ctx context.Context, patches []LeadPatch, batch, offset int) ([]BulkUpdatedLead, []BulkUpdateError) {
+	var failed []BulkUpdateError
+
+	valid := make([]LeadPatch, 0, len(patches))
+	positions := make([]int, 0, len(patches))
+	for i := range patches {
+		if msg := validatePatch(&patches[i]); msg != "" {
+			failed = append(failed, BulkUpdateError{Index: offset + i, ID: patches[i].ID, Message: msg, Patch: &patches[i], Batch: batch})
+			continue
+		}
+		valid = append(valid, patches[i])
+		positions = append(positions, i)
+	}
+
+	if len(valid) == 0 {
+		return nil, failed
+	}
+
+	c.inst.BatchSent(ctx, len(valid))
+
+	result, err := c.bulkUpdate(ctx, valid)
+	if err != nil {
+		for j := range valid {
+			failed = append(failed, BulkUpdateError{
+				Index:   offset + positions[j],
+				ID:      valid[j].ID,
+				Message: err.Error(),
+				Patch:   &patches[positions[j]],
+				Batch:   batch,
+				Err:     err,
+			})
+		}
+		return nil, failed
+	}
+
+	updated := make([]BulkUpdatedLead, 0, len(result.Updated))
+	for _, r := range result.Updated {
+		if r.Index < 0 || r.Index >= len(valid) {
+			continue
+		}
+		r.Patch = &patches[positions[r.Index]]
+		r.Batch = batch
+		r.Index = offset + positions[r.Index]
+		updated = append(updated, r)
+	}
+	for _, e := range result.Errors {
+		if e.Index < 0 || e.Index >= len(valid) {
+			continue
+		}
+		e.Patch = &patches[positions[e.Index]]
+		e.ID = e.Patch.ID
+		e.Batch = batch
+		e.Index = offset + positions[e.Index]
+		failed = append(failed, e)
+	}
+
+	return updated, failed
+}
+
+// deleteBatch deletes ids with BulkDelete and returns per-ID outcomes.
+// Indices are offset by offset, and empty IDs or IDs belonging to a failed
+// request are reported as errors instead of being dropped.
+func (c *Client) deleteBatch(ctx context.Context, ids []string, batch, offset int) ([]BulkDeletedLead, []BulkDeleteError) {
+	var failed []BulkDeleteError
+
+	valid := make([]string, 0, len(ids))
+	positions := make([]int, 0, len(ids))
+	for i, id := range ids {
+		if id == "" {
+			failed = append(failed, BulkDeleteError{Index: offset + i, ID: id, Message: "id is required", Batch: batch})
+			continue
+		}
+		valid = append(valid, id)
+		positions = append(positions, i)
+	}
+
+	if len(valid) == 0 {
+		return nil, failed
+	}
+
+	c.inst.BatchSent(ctx, len(valid))
+
+	result, err := c.BulkDelete(ctx, valid)
+	if err != nil {
+		for j, id := range valid {
+			failed = append(failed, BulkDeleteError{
+				Index:   offset + positions[j],
+				ID:      id,
+				Message: err.Error(),
+				Batch:   batch,
+				Err:     err,
+			})
+		}
+		return nil, failed
+	}
+
+	deleted := make([]BulkDeletedLead, 0, len(result.Deleted))
+	for _, r := range result.Deleted {
+		if r.Index < 0 || r.Index >= len(valid) {
+			continue
+		}
+		r.ID = valid[r.Index]
+		r.Batch = batch
+		r.Index = offset + positions[r.Index]
+		deleted = append(deleted, r)
+	}
+	for _, e := range result.Errors {
+		if e.Index < 0 || e.Index >= len(valid) {
+			continue
+		}
+		e.ID = valid[e.Index]
+		e.Batch = batch
+		e.Index = offset + positions[e.Index]
+		failed = append(failed, e)
+	}
+
+	return deleted, failed
+}
+
+// validatePatch returns a description of what is wrong with patch, or an empty string.
+func validatePatch(patch *LeadPatch) string {
+	switch {
+	case patch.ID == "":
+		return "id is required"
+	case patch.Input == nil:
+		return "input is required"
+	}
+
+	if err := patch.Input.validate(); err != nil {
+		return strings.TrimPrefix(err.Error(), "leadsdb: ")
+	}
+	return ""
+}
@@ -0,0 +1,373 @@
+package leadsdb_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestBulkUpdate(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	a := srv.AddLead(leadsdb.Lead{Name: "A", Source: "test", Tags: []string{"new"}})
+	b := srv.AddLead(leadsdb.Lead{Name: "B", Source: "test"})
+	client := srv.Client()
+
+	result, err := client.BulkUpdate(context.Background(), []leadsdb.LeadPatch{
+		{ID: a.ID, Input: &leadsdb.UpdateLeadInput{AddTags: []string{"contacted"}, RemoveTags: []string{"new"}}},
+		{ID: "missing", Input: &leadsdb.UpdateLeadInput{City: leadsdb.Ptr("Berlin")}},
+		{ID: b.ID, Input: &leadsdb.UpdateLeadInput{City: leadsdb.Ptr("Paris")}},
+	})
+	if err != nil {
+		t.Fatalf("BulkUpdate: %v", err)
+	}
+	if result.Success != 2 || result.Failed != 1 {
+		t.Fatalf("success %d, failed %d; want 2 and 1", result.Success, result.Failed)
+	}
+	if e := result.Errors[0]; e.Index != 1 || e.ID != "missing" {
+		t.Errorf("error %+v, want index 1 for the missing lead", e)
+	}
+
+	leads := srv.Leads()
+	if len(leads[0].Tags) != 1 || leads[0].Tags[0] != "contacted" {
+		t.Errorf("tags = %q, want [contacted]", leads[0].Tags)
+	}
+	if leads[1].City != "Paris" {
+		t.Errorf("city = %q, want Paris", leads[1].City)
+	}
+}
+
+func TestBulkDelete(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	a := srv.AddLead(leadsdb.Lead{Name: "A", Source: "test"})
+	b := srv.AddLead(leadsdb.Lead{Name: "B", Source: "test"})
+	client := srv.Client(leadsdb.WithRetryPolicy(leadsdbtest.NoDelay{}))
+
+	// The first response is lost after the server has deleted the leads; the
+	// retry must report the original outcome rather than "not found".
+	srv.InjectFault(leadsdbtest.Fault{TruncateAfter: 1})
+
+	result, err := client.BulkDelete(context.Background(), []string{a.ID, "missing", b.ID})
+	if err != nil {
+		t.Fatalf("BulkDelete: %v", err)
+	}
+	if result.Success != 2 || result.Failed != 1 || result.Errors[0].ID != "missing" {
+		t.Fatalf("got %+v, want two deleted and the missing ID failed", result)
+	}
+	if n := len(srv.Leads()); n != 0 {
+		t.Errorf("%d leads left, want 0", n)
+	}
+
+	requests := srv.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("server received %d requests, want 2", len(requests))
+	}
+	for _, req := range requests {
+		if req.Method != http.MethodPost || req.Path != "/leads/batch/delete" {
+			t.Errorf("request %s %s, want POST /leads/batch/delete", req.Method, req.Path)
+		}
+	}
+}
+
+func TestBulkFromChanReportsInvalidItems(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	a := srv.AddLead(leadsdb.Lead{Name: "A", Source: "test"})
+	client := srv.Client()
+	ctx := context.Background()
+
+	patches := make(chan leadsdb.LeadPatch, 2)
+	patches <- leadsdb.LeadPatch{ID: a.ID, Input: &leadsdb.UpdateLeadInput{City: leadsdb.Ptr("Paris")}}
+	patches <- leadsdb.LeadPatch{Input: &leadsdb.UpdateLeadInput{City: leadsdb.Ptr("Rome")}}
+	close(patches)
+
+	updated, updateErrs := client.BulkUpdateFromChan(ctx, patches, leadsdb.WithFlushTimeout(time.Millisecond))
+	var updateFailed []*leadsdb.BulkUpdateError
+	for updated != nil || updateErrs != nil {
+		select {
+		case _, ok := <-updated:
+			if !ok {
+				updated = nil
+			}
+		case err, ok := <-updateErrs:
+			if !ok {
+				updateErrs = nil
+				continue
+			}
+			var e *leadsdb.BulkUpdateError
+			if !errors.As(err, &e) {
+				t.Fatalf("got %v, want a *BulkUpdateError", err)
+			}
+			updateFailed = append(updateFailed, e)
+		}
+	}
+
+	ids := make(chan string, 2)
+	ids <- a.ID
+	ids <- ""
+	close(ids)
+
+	deleted, deleteErrs := client.BulkDeleteFromChan(ctx, ids, leadsdb.WithFlushTimeout(time.Millisecond))
+	var deleteFailed []*leadsdb.BulkDeleteError
+	for deleted != nil || deleteErrs != nil {
+		select {
+		case _, ok := <-deleted:
+			if !ok {
+				deleted = nil
+			}
+		case err, ok := <-deleteErrs:
+			if !ok {
+				deleteErrs = nil
+				continue
+			}
+			var e *leadsdb.BulkDeleteError
+			if !errors.As(err, &e) {
+				t.Fatalf("got %v, want a *BulkDeleteError", err)
+			}
+			deleteFailed = append(deleteFailed, e)
+		}
+	}
+
+	if len(updateFailed) != 1 || len(deleteFailed) != 1 {
+		t.Fatalf("got %d update and %d delete errors, want 1 each", len(updateFailed), len(deleteFailed))
+	}
+	u, d := updateFailed[0], deleteFailed[0]
+	if u.Index != 1 || u.ID != "" || u.Message != "id is required" || u.Err != nil || u.Patch == nil {
+		t.Errorf("update error %+v, want index 1 with the patch and no batch error", u)
+	}
+	if d.Index != u.Index || d.ID != u.ID || d.Message != u.Message || d.Batch != u.Batch || d.Err != nil {
+		t.Errorf("delete error %+v, want the same shape as update error %+v", d, u)
+	}
+	if n := len(srv.Leads()); n != 0 {
+		t.Errorf("%d leads left, want the valid ID deleted", n)
+	}
+}
+
+func TestBulkCreateFromChanConcurrency(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	// Slow batch requests down so that several of them overlap.
+	var (
+		mu             sync.Mutex
+		inflight, peak int
+	)
+	slow := func(next leadsdb.Doer) leadsdb.Doer {
+		return leadsdb.DoerFunc(func(ctx context.Context, req *leadsdb.Request) (*leadsdb.Response, error) {
+			mu.Lock()
+			inflight++
+			peak = max(peak, inflight)
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				inflight--
+				mu.Unlock()
+			}()
+
+			time.Sleep(50 * time.Millisecond)
+			return next.Do(ctx, req)
+		})
+	}
+	client := srv.Client(leadsdb.WithMiddleware(slow))
+
+	const total = 350
+	in := make(chan *leadsdb.Lead)
+	go func() {
+		defer close(in)
+		for i := range total {
+			in <- &leadsdb.Lead{Name: fmt.Sprintf("lead-%d", i), Source: "test"}
+		}
+	}()
+
+	results, errs := client.BulkCreateFromChan(context.Background(), in, leadsdb.WithConcurrency(3))
+
+	seen := make(map[int]bool)
+	for r := range results {
+		if want := fmt.Sprintf("lead-%d", r.Index); r.Lead == nil || r.Lead.Name != want {
+			t.Fatalf("result at index %d carries %+v, want %s", r.Index, r.Lead, want)
+		}
+		if r.Batch != r.Index/100 {
+			t.Errorf("result at index %d reports batch %d, want %d", r.Index, r.Batch, r.Index/100)
+		}
+		if seen[r.Index] {
+			t.Fatalf("index %d reported twice", r.Index)
+		}
+		seen[r.Index] = true
+	}
+	for err := range errs {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(seen) != total {
+		t.Fatalf("got %d results, want %d", len(seen), total)
+	}
+	if got := len(srv.Leads()); got != total {
+		t.Fatalf("server stored %d leads, want %d", got, total)
+	}
+	if peak < 2 || peak > 3 {
+		t.Fatalf("peak of %d concurrent requests, want 2 or 3", peak)
+	}
+}
+
+func TestBulkCreateFromChanCancel(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	// Hold every request until the context is cancelled.
+	started := make(chan struct{}, 10)
+	hang := func(next leadsdb.Doer) leadsdb.Doer {
+		return leadsdb.DoerFunc(func(ctx context.Context, req *leadsdb.Request) (*leadsdb.Response, error) {
+			started <- struct{}{}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	}
+	client := srv.Client(leadsdb.WithMiddleware(hang))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan *leadsdb.Lead)
+	results, errs := client.BulkCreateFromChan(ctx, in, leadsdb.WithConcurrency(2))
+
+	// Fill two batches so both request slots are taken; the input stays open.
+	for i := range 200 {
+		in <- &leadsdb.Lead{Name: fmt.Sprintf("lead-%d", i), Source: "test"}
+	}
+	for range 2 {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("batches were not submitted")
+		}
+	}
+
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range results {
+		}
+		for range errs {
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("channels were not closed after the context was cancelled")
+	}
+}
+
+func TestBulkCreateAllIndices(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	client := srv.Client()
+
+	// Blank names pass client validation but are rejected by the server;
+	// a missing source is caught by the client.
+	rejected := map[int]bool{5: true, 150: true, 249: true}
+	invalid := map[int]bool{42: true, 199: true}
+	leads := make([]*leadsdb.Lead, 250)
+	for i := range leads {
+		leads[i] = &leadsdb.Lead{Name: fmt.Sprintf("lead-%d", i), Source: "test"}
+		switch {
+		case rejected[i]:
+			leads[i].Name = " "
+		case invalid[i]:
+			leads[i].Source = ""
+		}
+	}
+
+	result, err := client.BulkCreateAll(context.Background(), leads, leadsdb.WithConcurrency(3))
+	if err != nil {
+		t.Fatalf("BulkCreateAll: %v", err)
+	}
+	if result.Total != 250 || result.Success != 245 || result.Failed != 5 {
+		t.Fatalf("got total %d, success %d, failed %d, want 250, 245, 5", result.Total, result.Success, result.Failed)
+	}
+
+	var failed []int
+	for _, e := range result.Errors {
+		if e.Lead != leads[e.Index] || e.Batch != e.Index/100 {
+			t.Fatalf("error at index %d refers to lead %+v in batch %d", e.Index, e.Lead, e.Batch)
+		}
+		failed = append(failed, e.Index)
+	}
+	if want := []int{5, 42, 150, 199, 249}; fmt.Sprint(failed) != fmt.Sprint(want) {
+		t.Fatalf("got errors at %v, want %v", failed, want)
+	}
+
+	stored := make(map[string]string)
+	for _, lead := range srv.Leads() {
+		stored[lead.ID] = lead.Name
+	}
+	for i, r := range result.Created {
+		if i > 0 && r.Index <= result.Created[i-1].Index {
+			t.Fatalf("created results are not ordered: %d after %d", r.Index, result.Created[i-1].Index)
+		}
+		if r.Lead != leads[r.Index] || stored[r.ID] != leads[r.Index].Name {
+			t.Fatalf("created result at index %d refers to %q, want %q", r.Index, stored[r.ID], leads[r.Index].Name)
+		}
+	}
+}
+
+func TestBulkCreateAllFailedBatch(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	client := srv.Client()
+	srv.FailNext(http.StatusBadRequest, 1)
+
+	leads := make([]*leadsdb.Lead, 150)
+	for i := range leads {
+		leads[i] = &leadsdb.Lead{Name: fmt.Sprintf("lead-%d", i), Source: "test"}
+	}
+
+	result, err := client.BulkCreateAll(context.Background(), leads)
+	if err != nil {
+		t.Fatalf("BulkCreateAll: %v", err)
+	}
+	if len(result.Errors) != 100 || len(result.Created) != 50 {
+		t.Fatalf("got %d errors and %d created, want 100 and 50", len(result.Errors), len(result.Created))
+	}
+	for i, e := range result.Errors {
+		if e.Index != i || e.Batch != 0 || e.Err == nil {
+			t.Fatalf("error %d: got index %d, batch %d, err %v", i, e.Index, e.Batch, e.Err)
+		}
+	}
+	for i, r := range result.Created {
+		if r.Index != 100+i || r.Batch != 1 {
+			t.Fatalf("created %d: got index %d, batch %d", i, r.Index, r.Batch)
+		}
+	}
+}
+
+func TestBulkCreateAllCancel(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	client := srv.Client()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	leads := []*leadsdb.Lead{{Name: "Acme", Source: "test"}}
+	result, err := client.BulkCreateAll(ctx, leads)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if result != nil {
+		t.Fatalf("got result %+v, want nil", result)
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"fmt"
 	"io"
 	"iter"
-	"math/rand/v2"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"strconv"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -26,6 +28,8 @@ const (
 	DefaultBaseDelay = 1 * time.Second
 	// DefaultFlushTimeout is the default timeout for flushing partial batches.
 	DefaultFlushTimeout = 2 * time.Second
+	// MaxConflictRetries is the number of times UpdateFunc retries after a conflict.
+	MaxConflictRetries = 5
 	// maxJitter is the maximum jitter added to backoff.
 	maxJitter = 500 * time.Millisecond
 	// maxBatchSize is the maximum number of leads per batch.
@@ -34,10 +38,17 @@ const (
 
 // Client is the LeadsDB API client.
 type Client struct {
-	baseURL    string
-	apiKey     string
-	httpClient *http.Client
-	maxRetries int
+	baseURL     string
+	apiKey      string
+	httpClient  *http.Client
+	maxRetries  int
+	retryPolicy RetryPolicy
+	limiter     *rateLimiter
+	breaker     *breaker
+	middleware  []Middleware
+	doer        Doer
+	logger      *slog.Logger
+	inst        Instrumentation
 }
 
 // Option configures the Client.
@@ -46,9 +57,12 @@ type Option func(*Client)
 // New creates a new LeadsDB client with the given API key and options.
 func New(apiKey string, opts ...Option) *Client {
 	c := &Client{
-		baseURL:    DefaultBaseURL,
-		apiKey:     apiKey,
-		maxRetries: DefaultMaxRetries,
+		baseURL:     DefaultBaseURL,
+		apiKey:      apiKey,
+		maxRetries:  DefaultMaxRetries,
+		retryPolicy: DefaultRetryPolicy{},
+		logger:      slog.New(slog.DiscardHandler),
+		inst:        NopInstrumentation{},
 		httpClient: &http.Client{
 			Timeout: DefaultTimeout,
 		},
@@ -58,6 +72,8 @@ func New(apiKey string, opts ...Option) *Client {
 		opt(c)
 	}
 
+	c.doer = chain(c.middleware, DoerFunc(c.transport))
+
 	return c
 }
 
@@ -104,24 +120,76 @@ func (c *Client) Get(ctx context.Context, id string) (*Lead, error) {
 }
 
 // Update partially updates a lead by ID.
-func (c *Client) Update(ctx context.Context, id string, input *UpdateLeadInput) (*Lead, error) {
+// Pass IfMatch or IfUnmodifiedSince to update only a lead that has not changed.
+func (c *Client) Update(ctx context.Context, id string, input *UpdateLeadInput, opts ...UpdateOption) (*Lead, error) {
 	if id == "" {
 		return nil, errors.New("leadsdb: id is required")
 	}
 	if input == nil {
 		return nil, errors.New("leadsdb: input is required")
 	}
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
 
 	var lead Lead
-	if err := c.do(ctx, http.MethodPatch, "/leads/"+id, input, &lead); err != nil {
+	if err := c.do(ctx, http.MethodPatch, "/leads/"+id, input, &lead, requestOptions(opts)...); err != nil {
 		return nil, err
 	}
 
 	return &lead, nil
 }
 
+// UpdateFunc performs a read-modify-write of a lead. It reads the lead, passes
+// it to fn and applies the returned input only if the lead has not changed in
+// the meantime. When another writer got there first, the lead is read again
+// and fn is called again, up to MaxConflictRetries times. fn may return nil to
+// leave the lead unchanged, in which case no update is sent.
+//
+// The update is made conditional with IfMatch when the server returns an ETag.
+// Otherwise IfUnmodifiedSince is used, which only has a precision of one
+// second: a change made by another writer within the same second as the read
+// is not detected and is overwritten. When the lead has neither an ETag nor
+// an UpdatedAt time, UpdateFunc fails without calling fn.
+func (c *Client) UpdateFunc(ctx context.Context, id string, fn func(lead *Lead) (*UpdateLeadInput, error)) (*Lead, error) {
+	var lastErr error
+	for range MaxConflictRetries + 1 {
+		lead, err := c.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		var precondition UpdateOption
+		switch {
+		case lead.ETag != "":
+			precondition = IfMatch(lead.ETag)
+		case !lead.UpdatedAt.IsZero():
+			precondition = IfUnmodifiedSince(lead.UpdatedAt)
+		default:
+			return nil, fmt.Errorf("leadsdb: lead %s has no ETag or updated_at to make the update conditional", id)
+		}
+
+		input, err := fn(lead)
+		if err != nil {
+			return nil, err
+		}
+		if input == nil {
+			return lead, nil
+		}
+
+		updated, err := c.Update(ctx, id, input, precondition)
+		if !errors.Is(err, ErrConflict) {
+			return updated, err
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("leadsdb: lead %s kept changing: %w", id, lastErr)
+}
+
 // Create creates a new lead.
-func (c *Client) Create(ctx context.Context, lead *Lead) (*Lead, error) {
+// Retries reuse the same Idempotency-Key; see IdempotencyKey.
+func (c *Client) Create(ctx context.Context, lead *Lead, opts ...CreateOption) (*Lead, error) {
 	if lead == nil {
 		return nil, errors.New("leadsdb: lead is required")
 	}
@@ -133,7 +201,7 @@ func (c *Client) Create(ctx context.Context, lead *Lead) (*Lead, error) {
 	}
 
 	var created Lead
-	if err := c.do(ctx, http.MethodPost, "/leads", lead, &created); err != nil {
+	if err := c.do(ctx, http.MethodPost, "/leads", lead, &created, requestOptions(opts)...); err != nil {
 		return nil, err
 	}
 
@@ -150,7 +218,8 @@ func (c *Client) Delete(ctx context.Context, id string) error {
 }
 
 // CreateNote creates a note for a lead.
-func (c *Client) CreateNote(ctx context.Context, leadID, content string) (*Note, error) {
+// Retries reuse the same Idempotency-Key; see IdempotencyKey.
+func (c *Client) CreateNote(ctx context.Context, leadID, content string, opts ...CreateOption) (*Note, error) {
 	if leadID == "" {
 		return nil, errors.New("leadsdb: leadID is required")
 	}
@@ -159,7 +228,7 @@ func (c *Client) CreateNote(ctx context.Context, leadID, content string) (*Note,
 	}
 
 	var note Note
-	if err := c.do(ctx, http.MethodPost, "/leads/"+leadID+"/notes", createNoteRequest{Content: content}, &note); err != nil {
+	if err := c.do(ctx, http.MethodPost, "/leads/"+leadID+"/notes", createNoteRequest{Content: content}, &note, requestOptions(opts)...); err != nil {
 		return nil, err
 	}
 
@@ -206,78 +275,181 @@ func (c *Client) DeleteNote(ctx context.Context, noteID string) error {
 	return c.do(ctx, http.MethodDelete, "/leads/notes/"+noteID, nil, nil)
 }
 
-// ExportFormat defines the format for exporting leads.
-type ExportFormat string
+// BulkCreate creates up to 100 leads in a single request.
+// Retries reuse the same Idempotency-Key; see IdempotencyKey.
+func (c *Client) BulkCreate(ctx context.Context, leads []*Lead, opts ...CreateOption) (*BulkCreateResult, error) {
+	if len(leads) == 0 {
+		return nil, errors.New("leadsdb: leads is required")
+	}
+	if len(leads) > maxBatchSize {
+		return nil, errors.New("leadsdb: maximum 100 leads allowed")
+	}
+	if err := validateLeads(leads); err != nil {
+		return nil, err
+	}
 
-const (
-	ExportCSV  ExportFormat = "csv"
-	ExportJSON ExportFormat = "json"
-)
+	return c.bulkCreate(ctx, leads, requestOptions(opts)...)
+}
 
-// Export exports leads in the specified format and returns a reader.
-// The caller must close the reader when done.
-func (c *Client) Export(ctx context.Context, format ExportFormat) (io.ReadCloser, error) {
-	if format == "" {
-		format = ExportCSV
-	}
+// bulkCreate sends leads, which have already been validated, in one request.
+func (c *Client) bulkCreate(ctx context.Context, leads []*Lead, opts ...func(*requestConfig)) (*BulkCreateResult, error) {
+	body := struct {
+		Leads []*Lead `json:"leads"`
+	}{Leads: leads}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/leads/export?format="+string(format), nil)
-	if err != nil {
+	var result BulkCreateResult
+	if err := c.do(ctx, http.MethodPost, "/leads/batch", body, &result, opts...); err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("X-API-Key", c.apiKey)
+	return &result, nil
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+// BulkCreateAll creates any number of leads, splitting them into batches of 100
+// that are submitted concurrently according to WithConcurrency.
+//
+// Indices in the returned result refer to positions in leads. Leads that fail
+// validation are reported in Errors like the ones the server rejects, and when
+// a whole batch fails, every lead in it is reported with the batch error message.
+// When ctx ends, BulkCreateAll stops submitting batches and returns ctx.Err();
+// batches sent before that may have been created.
+func (c *Client) BulkCreateAll(ctx context.Context, leads []*Lead, opts ...BulkCreateAllOption) (*BulkCreateResult, error) {
+	if len(leads) == 0 {
+		return nil, errors.New("leadsdb: leads is required")
 	}
 
-	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
-		return resp.Body, nil
+	cfg := newBulkConfig()
+	for _, opt := range opts {
+		opt.applyCreateAll(cfg)
 	}
 
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
+	result := &BulkCreateResult{
+		Total:   len(leads),
+		Created: make([]BulkLeadResult, 0, len(leads)),
+	}
 
-	apiErr := &APIError{StatusCode: resp.StatusCode}
-	if len(body) > 0 {
-		_ = json.Unmarshal(body, apiErr)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	offsets := make(chan int)
+
+	for range cfg.concurrency {
+		wg.Go(func() {
+			for offset := range offsets {
+				batch := leads[offset:min(offset+maxBatchSize, len(leads))]
+				created, failed := c.createBatch(ctx, batch, offset/maxBatchSize, offset)
+
+				mu.Lock()
+				result.Created = append(result.Created, created...)
+				result.Errors = append(result.Errors, failed...)
+				mu.Unlock()
+			}
+		})
 	}
-	if apiErr.Message == "" {
-		apiErr.Message = http.StatusText(resp.StatusCode)
+
+send:
+	for offset := 0; offset < len(leads); offset += maxBatchSize {
+		select {
+		case offsets <- offset:
+		case <-ctx.Done():
+			break send
+		}
 	}
+	close(offsets)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(result.Created, func(a, b BulkLeadResult) int { return a.Index - b.Index })
+	slices.SortFunc(result.Errors, func(a, b BulkLeadError) int { return a.Index - b.Index })
+	result.Success = len(result.Created)
+	result.Failed = len(result.Errors)
 
-	return nil, apiErr
+	return result, nil
 }
 
-// BulkCreate creates up to 100 leads in a single request.
-func (c *Client) BulkCreate(ctx context.Context, leads []*Lead) (*BulkCreateResult, error) {
-	if len(leads) == 0 {
-		return nil, errors.New("leadsdb: leads is required")
+// createBatch creates leads in one request and returns per-lead outcomes.
+// Indices are offset by offset, and leads that fail validation or belong to a
+// failed request are reported as errors instead of being dropped.
+func (c *Client) createBatch(ctx context.Context, leads []*Lead, batch, offset int) ([]BulkLeadResult, []BulkLeadError) {
+	var failed []BulkLeadError
+
+	valid := make([]*Lead, 0, len(leads))
+	positions := make([]int, 0, len(leads))
+	for i, lead := range leads {
+		if msg := validateLead(lead); msg != "" {
+			failed = append(failed, BulkLeadError{Index: offset + i, Message: msg, Lead: lead, Batch: batch})
+			continue
+		}
+		valid = append(valid, lead)
+		positions = append(positions, i)
 	}
-	if len(leads) > maxBatchSize {
-		return nil, errors.New("leadsdb: maximum 100 leads allowed")
+
+	if len(valid) == 0 {
+		return nil, failed
 	}
-	for i, lead := range leads {
-		if lead.Name == "" {
-			return nil, fmt.Errorf("leadsdb: lead at index %d: name is required", i)
+
+	c.inst.BatchSent(ctx, len(valid))
+
+	result, err := c.bulkCreate(ctx, valid)
+	if err != nil {
+		for j, lead := range valid {
+			failed = append(failed, BulkLeadError{
+				Index:   offset + positions[j],
+				Message: err.Error(),
+				Lead:    lead,
+				Batch:   batch,
+				Err:     err,
+			})
 		}
-		if lead.Source == "" {
-			return nil, fmt.Errorf("leadsdb: lead at index %d: source is required", i)
+		return nil, failed
+	}
+
+	created := make([]BulkLeadResult, 0, len(result.Created))
+	for _, r := range result.Created {
+		if r.Index < 0 || r.Index >= len(valid) {
+			continue
 		}
+		r.Lead = valid[r.Index]
+		r.Batch = batch
+		r.Index = offset + positions[r.Index]
+		created = append(created, r)
+	}
+	for _, e := range result.Errors {
+		if e.Index < 0 || e.Index >= len(valid) {
+			continue
+		}
+		e.Lead = valid[e.Index]
+		e.Batch = batch
+		e.Index = offset + positions[e.Index]
+		failed = append(failed, e)
 	}
 
-	body := struct {
-		Leads []*Lead `json:"leads"`
-	}{Leads: leads}
+	return created, failed
+}
 
-	var result BulkCreateResult
-	if err := c.do(ctx, http.MethodPost, "/leads/batch", body, &result); err != nil {
-		return nil, err
+func validateLeads(leads []*Lead) error {
+	for i, lead := range leads {
+		if msg := validateLead(lead); msg != "" {
+			return fmt.Errorf("leadsdb: lead at index %d: %s", i, msg)
+		}
 	}
+	return nil
+}
 
-	return &result, nil
+// validateLead returns a description of what is missing from lead, or an empty string.
+func validateLead(lead *Lead) string {
+	switch {
+	case lead == nil:
+		return "lead is required"
+	case lead.Name == "":
+		return "name is required"
+	case lead.Source == "":
+		return "source is required"
+	default:
+		return ""
+	}
 }
 
 // SortOrder defines the order for sorting.
@@ -307,6 +479,27 @@ type listConfig struct {
 	sortBy    string
 	sortOrder SortOrder
 	filters   []filter
+	groups    []node
+}
+
+// setQueryParams adds the sort and filter parameters to params. It fails when
+// a filter group cannot be expressed with the API's filters.
+func (cfg *listConfig) setQueryParams(params url.Values) error {
+	filters, err := apiFilters(cfg.filters, cfg.groups)
+	if err != nil {
+		return err
+	}
+
+	if cfg.sortBy != "" {
+		params.Set("sort_by", cfg.sortBy)
+		if cfg.sortOrder != "" {
+			params.Set("sort_order", string(cfg.sortOrder))
+		}
+	}
+	for _, f := range filters {
+		params.Add("filter", f.String())
+	}
+	return nil
 }
 
 type limitOption int
@@ -353,14 +546,8 @@ func (c *Client) List(ctx context.Context, opts ...ListOption) (*ListResult, err
 	if cfg.cursor != "" {
 		params.Set("cursor", cfg.cursor)
 	}
-	if cfg.sortBy != "" {
-		params.Set("sort_by", cfg.sortBy)
-		if cfg.sortOrder != "" {
-			params.Set("sort_order", string(cfg.sortOrder))
-		}
-	}
-	for _, f := range cfg.filters {
-		params.Add("filter", f.String())
+	if err := cfg.setQueryParams(params); err != nil {
+		return nil, err
 	}
 
 	path := "/leads"
@@ -437,6 +624,7 @@ func (c *Client) iterate(ctx context.Context, opts []ListOption) iter.Seq2[*Lead
 				yield(nil, err)
 				return
 			}
+			c.inst.PageFetched(ctx, len(result.Leads))
 
 			for i := range result.Leads {
 				if !yield(&result.Leads[i], nil) {
@@ -452,39 +640,114 @@ func (c *Client) iterate(ctx context.Context, opts []ListOption) iter.Seq2[*Lead
 	}
 }
 
-// BulkCreateChanOption configures the BulkCreateFromChan method.
-type BulkCreateChanOption func(*bulkCreateChanConfig)
+// BulkCreateAllOption configures BulkCreateAll.
+type BulkCreateAllOption interface {
+	applyCreateAll(*bulkConfig)
+}
 
-type bulkCreateChanConfig struct {
+// BulkChanOption configures the channel-driven bulk methods
+// BulkCreateFromChan, BulkUpdateFromChan and BulkDeleteFromChan.
+type BulkChanOption interface {
+	applyChan(*bulkConfig)
+}
+
+// BulkCreateChanOption is the former name of BulkChanOption.
+//
+// Deprecated: Use BulkChanOption.
+type BulkCreateChanOption = BulkChanOption
+
+type bulkConfig struct {
 	flushTimeout time.Duration
+	concurrency  int
 }
 
-// WithFlushTimeout sets the timeout for flushing partial batches.
-func WithFlushTimeout(d time.Duration) BulkCreateChanOption {
-	return func(cfg *bulkCreateChanConfig) {
-		cfg.flushTimeout = d
+// newBulkConfig returns the default configuration of the bulk methods.
+func newBulkConfig() *bulkConfig {
+	return &bulkConfig{
+		flushTimeout: DefaultFlushTimeout,
+		concurrency:  1,
+	}
+}
+
+// bulkChanConfig applies opts to the default configuration.
+func bulkChanConfig(opts []BulkChanOption) *bulkConfig {
+	cfg := newBulkConfig()
+	for _, opt := range opts {
+		opt.applyChan(cfg)
 	}
+	return cfg
+}
+
+type flushTimeoutOption time.Duration
+
+func (o flushTimeoutOption) applyChan(cfg *bulkConfig) {
+	cfg.flushTimeout = time.Duration(o)
+}
+
+// WithFlushTimeout sets the timeout for flushing partial batches.
+func WithFlushTimeout(d time.Duration) BulkChanOption {
+	return flushTimeoutOption(d)
+}
+
+// ConcurrencyOption is the option returned by WithConcurrency. It can be passed
+// to BulkCreateAll and to the channel-driven bulk methods.
+type ConcurrencyOption int
+
+func (o ConcurrencyOption) applyCreateAll(cfg *bulkConfig) {
+	cfg.concurrency = max(int(o), 1)
+}
+
+func (o ConcurrencyOption) applyChan(cfg *bulkConfig) {
+	cfg.concurrency = max(int(o), 1)
+}
+
+// WithConcurrency sets the maximum number of batches submitted at the same time.
+// Values below 1 are treated as 1.
+func WithConcurrency(n int) ConcurrencyOption {
+	return ConcurrencyOption(n)
 }
 
 // BulkCreateFromChan reads leads from the input channel and creates them in batches of 100.
 // It returns a channel of results for each successfully created lead and a channel for errors.
 // Both channels are closed when all leads are processed or the context is cancelled.
-func (c *Client) BulkCreateFromChan(ctx context.Context, leads <-chan *Lead, opts ...BulkCreateChanOption) (<-chan *BulkLeadResult, <-chan error) {
-	cfg := &bulkCreateChanConfig{
-		flushTimeout: DefaultFlushTimeout,
-	}
-	for _, opt := range opts {
-		opt(cfg)
-	}
+//
+// Use WithConcurrency to keep several batches in flight; reading from the input
+// channel pauses while all of them are busy. With more than one batch in flight,
+// results from different batches may arrive out of order.
+//
+// Every lead that is not created produces a *BulkLeadError on the error channel
+// carrying the original lead, including leads that fail validation and leads in
+// a batch whose request failed. Indices in results and errors are positions in
+// the input stream.
+func (c *Client) BulkCreateFromChan(ctx context.Context, leads <-chan *Lead, opts ...BulkChanOption) (<-chan *BulkLeadResult, <-chan error) {
+	return streamBatches(ctx, leads, bulkChanConfig(opts), c.createBatch)
+}
 
-	results := make(chan *BulkLeadResult)
+// streamBatches reads items from in and passes them to process in batches of
+// up to maxBatchSize, flushing partial batches after cfg.flushTimeout. Up to
+// cfg.concurrency batches are processed at the same time. The outcomes of each
+// batch are sent on the returned channels, which are closed when in is closed
+// and all batches are done, or when ctx is cancelled.
+func streamBatches[T, R, E any, PE interface {
+	*E
+	error
+}](ctx context.Context, in <-chan T, cfg *bulkConfig, process func(ctx context.Context, items []T, batch, offset int) ([]R, []E)) (<-chan *R, <-chan error) {
+	results := make(chan *R)
 	errs := make(chan error, 1)
 
 	go func() {
 		defer close(results)
 		defer close(errs)
 
-		batch := make([]*Lead, 0, maxBatchSize)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		// inflight bounds the number of concurrent requests. Acquiring a slot
+		// blocks the reader, which applies backpressure to the input channel.
+		inflight := make(chan struct{}, cfg.concurrency)
+
+		batch := make([]T, 0, maxBatchSize)
+		batchNum, offset := 0, 0
 		timer := time.NewTimer(cfg.flushTimeout)
 		timer.Stop()
 		defer timer.Stop()
@@ -494,33 +757,38 @@ func (c *Client) BulkCreateFromChan(ctx context.Context, leads <-chan *Lead, opt
 				return
 			}
 
-			result, err := c.BulkCreate(ctx, batch)
-			if err != nil {
-				select {
-				case errs <- err:
-				case <-ctx.Done():
-				}
-				batch = batch[:0]
+			submitted, num, off := batch, batchNum, offset
+			batch = make([]T, 0, maxBatchSize)
+			batchNum++
+			offset += len(submitted)
+
+			select {
+			case inflight <- struct{}{}:
+			case <-ctx.Done():
 				return
 			}
 
-			for i := range result.Created {
-				select {
-				case results <- &result.Created[i]:
-				case <-ctx.Done():
-					return
-				}
-			}
+			wg.Go(func() {
+				defer func() { <-inflight }()
 
-			for i := range result.Errors {
-				select {
-				case errs <- fmt.Errorf("index %d: %s", result.Errors[i].Index, result.Errors[i].Message):
-				case <-ctx.Done():
-					return
+				done, failed := process(ctx, submitted, num, off)
+
+				for i := range done {
+					select {
+					case results <- &done[i]:
+					case <-ctx.Done():
+						return
+					}
 				}
-			}
 
-			batch = batch[:0]
+				for i := range failed {
+					select {
+					case errs <- PE(&failed[i]):
+					case <-ctx.Done():
+						return
+					}
+				}
+			})
 		}
 
 		for {
@@ -529,14 +797,14 @@ func (c *Client) BulkCreateFromChan(ctx context.Context, leads <-chan *Lead, opt
 				return
 			case <-timer.C:
 				flush()
-			case lead, ok := <-leads:
+			case item, ok := <-in:
 				if !ok {
 					timer.Stop()
 					flush()
 					return
 				}
 
-				batch = append(batch, lead)
+				batch = append(batch, item)
 				if len(batch) == 1 {
 					timer.Reset(cfg.flushTimeout)
 				}
@@ -551,20 +819,78 @@ func (c *Client) BulkCreateFromChan(ctx context.Context, leads <-chan *Lead, opt
 	return results, errs
 }
 
-func (c *Client) do(ctx context.Context, method, path string, body, result any) error {
+func (c *Client) do(ctx context.Context, method, path string, body, result any, opts ...func(*requestConfig)) error {
+	cfg := &requestConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	resp, err := c.send(ctx, method, path, body, cfg.header(method), false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if result == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return err
+	}
+	if v, ok := result.(etagSetter); ok {
+		v.setETag(resp.Header.Get("ETag"))
+	}
+	return nil
+}
+
+// etagSetter is implemented by results that keep the ETag of their response.
+type etagSetter interface {
+	setETag(etag string)
+}
+
+// send performs a request through the middleware and returns the response.
+// Headers in header are added to the request, replacing the defaults. Unless
+// stream is set, the response body is read before the call returns.
+// The caller must close the response body.
+func (c *Client) send(ctx context.Context, method, path string, body any, header http.Header, stream bool) (*Response, error) {
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return c.doer.Do(ctx, &Request{Method: method, Path: path, Body: body, Header: header, stream: stream})
+}
+
+// transport performs req, retrying failed attempts, and returns the first
+// successful response. Unless req.stream is set, the response body is read as
+// part of the attempt, so a connection lost while reading it is retried too.
+func (c *Client) transport(ctx context.Context, r *Request) (*Response, error) {
 	var bodyData []byte
-	if body != nil {
+	if r.Body != nil {
 		var err error
-		bodyData, err = json.Marshal(body)
+		bodyData, err = json.Marshal(r.Body)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
+	// At least one attempt is made, even with WithMaxRetries(0).
+	attempts := max(c.maxRetries, 1)
+
 	var lastErr error
-	for attempt := range c.maxRetries {
+	for attempt := range attempts {
 		if err := ctx.Err(); err != nil {
-			return err
+			return nil, err
+		}
+
+		if c.limiter != nil {
+			if err := c.limiter.wait(ctx); err != nil {
+				return nil, err
+			}
 		}
 
 		var bodyReader io.Reader
@@ -572,100 +898,182 @@ func (c *Client) do(ctx context.Context, method, path string, body, result any)
 			bodyReader = bytes.NewReader(bodyData)
 		}
 
-		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bodyReader)
+		req, err := http.NewRequestWithContext(ctx, r.Method, c.baseURL+r.Path, bodyReader)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		req.Header.Set("X-API-Key", c.apiKey)
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("Accept", "application/json")
-
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			lastErr = err
-			if !c.shouldRetry(0, err) {
-				return err
+		if traceparent, tracestate, ok := TraceFromContext(ctx); ok {
+			req.Header.Set("traceparent", traceparent)
+			if tracestate != "" {
+				req.Header.Set("tracestate", tracestate)
 			}
-			c.backoff(ctx, attempt, 0)
-			continue
+		}
+		for key, values := range r.Header {
+			req.Header[key] = values
 		}
 
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			return err
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.Path),
+			slog.Int("attempt", attempt+1),
 		}
 
-		if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
-			if result != nil && len(respBody) > 0 {
-				return json.Unmarshal(respBody, result)
+		if c.breaker != nil {
+			if err := c.breaker.allow(); err != nil {
+				c.logger.LogAttrs(ctx, slog.LevelWarn, "leadsdb: circuit breaker open", attrs...)
+				if lastErr != nil {
+					// The breaker opened between attempts; keep the failure that caused it.
+					return nil, fmt.Errorf("%w: %w", err, lastErr)
+				}
+				return nil, err
 			}
-			return nil
 		}
 
-		apiErr := &APIError{StatusCode: resp.StatusCode}
-		if len(respBody) > 0 {
-			_ = json.Unmarshal(respBody, apiErr)
-		}
-		if apiErr.Message == "" {
-			apiErr.Message = http.StatusText(resp.StatusCode)
-		}
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "leadsdb: sending request", append(attrs, slog.Any("header", logHeader(req.Header)))...)
 
-		if resp.StatusCode == http.StatusTooManyRequests {
-			if ra := resp.Header.Get("Retry-After"); ra != "" {
-				if seconds, err := strconv.Atoi(ra); err == nil {
-					apiErr.RetryAfter = seconds
-				}
+		start := time.Now()
+		resp, err := c.roundTrip(req, r.stream, RequestInfo{
+			Method:    r.Method,
+			Path:      requestPath(r.Path),
+			Attempt:   attempt + 1,
+			BytesSent: int64(len(bodyData)),
+		})
+		attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+
+		if err == nil {
+			if c.breaker != nil {
+				c.breaker.done(false, false)
 			}
+			c.logger.LogAttrs(ctx, slog.LevelDebug, "leadsdb: request succeeded", append(attrs, slog.Int("status", resp.StatusCode))...)
+			return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: resp.Body}, nil
 		}
 
-		lastErr = apiErr
+		lastErr = err
 
-		if !c.shouldRetry(resp.StatusCode, nil) {
-			return apiErr
+		var retry bool
+		var retryAfter time.Duration
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			if c.breaker != nil {
+				c.breaker.done(breakerFailure(apiErr.StatusCode), false)
+			}
+			retry = c.retryPolicy.ShouldRetry(r.Method, apiErr.StatusCode, nil)
+			retryAfter = apiErr.RetryDelay
+		} else {
+			if c.breaker != nil {
+				c.breaker.done(true, ctx.Err() != nil)
+			}
+			retry = c.retryPolicy.ShouldRetry(r.Method, 0, err)
 		}
 
-		c.backoff(ctx, attempt, apiErr.RetryAfter)
+		if !retry || attempt == attempts-1 {
+			c.logFailure(ctx, attrs, err)
+			return nil, err
+		}
+
+		if waitErr := c.retryWait(ctx, r, attrs, attempt, retryAfter, err); waitErr != nil {
+			err = fmt.Errorf("leadsdb: gave up retrying: %w: %w", waitErr, err)
+			c.logFailure(ctx, attrs, err)
+			return nil, err
+		}
 	}
 
-	return lastErr
+	return nil, lastErr
 }
 
-func (c *Client) shouldRetry(statusCode int, err error) bool {
+// roundTrip sends a single attempt of req and reports it to the client's
+// Instrumentation. Unsuccessful responses are returned as an *APIError.
+func (c *Client) roundTrip(req *http.Request, stream bool, info RequestInfo) (*http.Response, error) {
+	start := time.Now()
+	report := func(statusCode int, received int64, err error) {
+		info.StatusCode = statusCode
+		info.BytesReceived = received
+		info.Err = err
+		info.Duration = time.Since(start)
+		c.inst.RequestDone(req.Context(), info)
+	}
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return true
+		report(0, 0, err)
+		return nil, err
 	}
 
-	switch statusCode {
-	case http.StatusTooManyRequests,
-		http.StatusInternalServerError,
-		http.StatusBadGateway,
-		http.StatusServiceUnavailable,
-		http.StatusGatewayTimeout:
-		return true
-	default:
-		return false
+	if c.limiter != nil {
+		c.limiter.observe(resp)
+	}
+
+	body := &instrumentedBody{ReadCloser: resp.Body}
+	resp.Body = body
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		err := readAPIError(resp)
+		report(resp.StatusCode, body.n, err)
+		return nil, err
 	}
+
+	if stream {
+		body.report = func(n int64, err error) { report(resp.StatusCode, n, err) }
+		return resp, nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	report(resp.StatusCode, body.n, err)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
 }
 
-func (c *Client) backoff(ctx context.Context, attempt, retryAfter int) {
-	var delay time.Duration
+// requestPath returns path without its query string.
+func requestPath(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	return path
+}
+
+// readAPIError reads and closes the body of an unsuccessful response, and
+// returns it as an *APIError, or a *ValidationError for rejected input. The
+// error is the read error instead when the body cannot be read.
+func readAPIError(resp *http.Response) error {
+	defer resp.Body.Close()
 
-	if retryAfter > 0 {
-		delay = time.Duration(retryAfter) * time.Second
-	} else {
-		delay = DefaultBaseDelay << attempt
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
 	}
 
-	jitter := time.Duration(rand.Int64N(int64(maxJitter)))
-	delay += jitter
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var details struct {
+		Details []FieldError `json:"details"`
+	}
+	if len(respBody) > 0 {
+		_ = json.Unmarshal(respBody, apiErr)
+		_ = json.Unmarshal(respBody, &details)
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	if id := resp.Header.Get("X-Request-Id"); id != "" {
+		apiErr.RequestID = id
+	}
 
-	timer := time.NewTimer(delay)
-	defer timer.Stop()
+	if retryableStatus(resp.StatusCode) {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			apiErr.RetryDelay = d
+			apiErr.RetryAfter = int((d + time.Second - 1) / time.Second)
+		}
+	}
 
-	select {
-	case <-ctx.Done():
-	case <-timer.C:
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity {
+		return &ValidationError{APIError: apiErr, Fields: details.Details}
 	}
+
+	return apiErr
 }
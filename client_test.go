@@ -0,0 +1,95 @@
+package leadsdb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestZeroMaxRetriesMakesOneAttempt(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	srv.AddLead(leadsdb.Lead{Name: "Acme", Source: "test"})
+	client := srv.Client(leadsdb.WithMaxRetries(0))
+	ctx := context.Background()
+
+	result, err := client.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(result.Leads) != 1 {
+		t.Fatalf("got %d leads, want 1", len(result.Leads))
+	}
+
+	export, err := client.Export(ctx, leadsdb.ExportJSON)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if _, err := io.ReadAll(export); err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	export.Close()
+
+	srv.FailNext(http.StatusServiceUnavailable, 1)
+	_, err = client.List(ctx)
+	var apiErr *leadsdb.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got %v, want a 503 APIError", err)
+	}
+	if got := len(srv.Requests()); got != 3 {
+		t.Fatalf("server received %d requests, want 3", got)
+	}
+}
+
+func TestIdempotencyKeyIsReusedOnRetries(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		create func(*leadsdb.Client, *leadsdbtest.Server) error
+	}{
+		{"Create", func(c *leadsdb.Client, _ *leadsdbtest.Server) error {
+			_, err := c.Create(ctx, &leadsdb.Lead{Name: "Acme", Source: "test"}, leadsdb.IdempotencyKey("row-1"))
+			return err
+		}},
+		{"BulkCreate", func(c *leadsdb.Client, _ *leadsdbtest.Server) error {
+			_, err := c.BulkCreate(ctx, []*leadsdb.Lead{{Name: "Acme", Source: "test"}}, leadsdb.IdempotencyKey("row-1"))
+			return err
+		}},
+		{"CreateNote", func(c *leadsdb.Client, srv *leadsdbtest.Server) error {
+			lead := srv.AddLead(leadsdb.Lead{Name: "Acme", Source: "test"})
+			_, err := c.CreateNote(ctx, lead.ID, "called", leadsdb.IdempotencyKey("row-1"))
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := leadsdbtest.NewServer()
+			defer srv.Close()
+
+			client := srv.Client(leadsdb.WithRetryPolicy(leadsdbtest.NoDelay{}))
+			srv.FailNext(http.StatusServiceUnavailable, 1)
+
+			if err := tt.create(client, srv); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+
+			reqs := srv.Requests()
+			if len(reqs) != 2 {
+				t.Fatalf("recorded %d requests, want 2", len(reqs))
+			}
+			for i, r := range reqs {
+				if got := r.Header.Get("Idempotency-Key"); got != "row-1" {
+					t.Fatalf("attempt %d sent Idempotency-Key %q, want row-1", i+1, got)
+				}
+			}
+		})
+	}
+}
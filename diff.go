@@ -0,0 +1,227 @@
+package leadsdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Change describes a single difference between two leads.
+type Change struct {
+	// Field is the changed field.
+	Field UpdateField
+	// Name is the tag or attribute name for changes to FieldTags and FieldAttributes.
+	Name string
+	// Old and New are the values before and after the change; nil when absent.
+	// For tags they hold the removed or added tag, and for attributes the
+	// attribute value.
+	Old, New any
+}
+
+// String returns a human-readable description of the change, for example
+// `city: "Berlin" -> "Munich"` or `tags: added "vip"`.
+func (c Change) String() string {
+	if c.Field == FieldTags {
+		if c.New != nil {
+			return fmt.Sprintf("tags: added %q", c.Name)
+		}
+		return fmt.Sprintf("tags: removed %q", c.Name)
+	}
+
+	key := c.Field.updateFieldName()
+	if c.Name != "" {
+		key += "." + c.Name
+	}
+
+	return fmt.Sprintf("%s: %s -> %s", key, formatChangeValue(c.Old), formatChangeValue(c.New))
+}
+
+func formatChangeValue(v any) string {
+	if v == nil {
+		return "(none)"
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// Diff returns the update that turns old into new, or nil when they do not
+// differ, so the API call can be skipped.
+//
+// Only what new holds is applied: fields that are empty in new, and tags and
+// attributes that new lacks, are left unchanged, so diffing against a partial
+// lead never wipes data. Pass ClearEmpty to remove them instead.
+//
+// Tags and attributes are compared by name and sent as AddTags, RemoveTags,
+// SetAttributes and DeleteAttributes, so entries added to the stored lead by
+// others are kept. The order of tags is ignored. IDs, notes and timestamps
+// are not compared. A nil lead is treated as an empty one.
+func Diff(old, new *Lead, opts ...DiffOption) *UpdateLeadInput {
+	input, _ := diff(old, new, opts)
+	return input
+}
+
+// Changes lists the differences between old and new that Diff turns into an
+// update with the same options.
+func Changes(old, new *Lead, opts ...DiffOption) []Change {
+	_, changes := diff(old, new, opts)
+	return changes
+}
+
+// DiffOption configures Diff and Changes.
+type DiffOption func(*diffConfig)
+
+type diffConfig struct {
+	clearEmpty bool
+}
+
+// ClearEmpty makes Diff remove what new lacks: fields that are empty in new are
+// cleared, except Name and Source, which are required, and tags and attributes
+// that new does not have are removed. Use it when new is a complete lead.
+func ClearEmpty() DiffOption {
+	return func(cfg *diffConfig) {
+		cfg.clearEmpty = true
+	}
+}
+
+func diff(old, new *Lead, opts []DiffOption) (*UpdateLeadInput, []Change) {
+	cfg := &diffConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if old == nil {
+		old = &Lead{}
+	}
+	if new == nil {
+		new = &Lead{}
+	}
+
+	var (
+		input   UpdateLeadInput
+		changes []Change
+	)
+
+	diffString := func(field UpdateField, dst **string, before, after string) {
+		if before == after || (after == "" && (!cfg.clearEmpty || field == FieldName || field == FieldSource)) {
+			return
+		}
+
+		change := Change{Field: field}
+		if before != "" {
+			change.Old = before
+		}
+		if after == "" {
+			input.Clear = append(input.Clear, field)
+		} else {
+			*dst = Ptr(after)
+			change.New = after
+		}
+		changes = append(changes, change)
+	}
+
+	diffString(FieldName, &input.Name, old.Name, new.Name)
+	diffString(FieldSource, &input.Source, old.Source, new.Source)
+	diffString(FieldDescription, &input.Description, old.Description, new.Description)
+	diffString(FieldAddress, &input.Address, old.Address, new.Address)
+	diffString(FieldCity, &input.City, old.City, new.City)
+	diffString(FieldState, &input.State, old.State, new.State)
+	diffString(FieldCountry, &input.Country, old.Country, new.Country)
+	diffString(FieldPostalCode, &input.PostalCode, old.PostalCode, new.PostalCode)
+	diffString(FieldPhone, &input.Phone, old.Phone, new.Phone)
+	diffString(FieldEmail, &input.Email, old.Email, new.Email)
+	diffString(FieldWebsite, &input.Website, old.Website, new.Website)
+	diffString(FieldCategory, &input.Category, old.Category, new.Category)
+	diffString(FieldSourceID, &input.SourceID, old.SourceID, new.SourceID)
+	diffString(FieldLogoURL, &input.LogoURL, old.LogoURL, new.LogoURL)
+
+	if !ptrEqual(old.Coordinates, new.Coordinates) && (new.Coordinates != nil || cfg.clearEmpty) {
+		change := Change{Field: FieldCoordinates}
+		if old.Coordinates != nil {
+			change.Old = *old.Coordinates
+		}
+		if new.Coordinates == nil {
+			input.Clear = append(input.Clear, FieldCoordinates)
+		} else {
+			input.Coordinates = Ptr(*new.Coordinates)
+			change.New = *new.Coordinates
+		}
+		changes = append(changes, change)
+	}
+
+	if !ptrEqual(old.Rating, new.Rating) && (new.Rating != nil || cfg.clearEmpty) {
+		change := Change{Field: FieldRating}
+		if old.Rating != nil {
+			change.Old = *old.Rating
+		}
+		if new.Rating == nil {
+			input.Clear = append(input.Clear, FieldRating)
+		} else {
+			input.Rating = Ptr(*new.Rating)
+			change.New = *new.Rating
+		}
+		changes = append(changes, change)
+	}
+
+	if !ptrEqual(old.ReviewCount, new.ReviewCount) && (new.ReviewCount != nil || cfg.clearEmpty) {
+		change := Change{Field: FieldReviewCount}
+		if old.ReviewCount != nil {
+			change.Old = *old.ReviewCount
+		}
+		if new.ReviewCount == nil {
+			input.Clear = append(input.Clear, FieldReviewCount)
+		} else {
+			input.ReviewCount = Ptr(*new.ReviewCount)
+			change.New = *new.ReviewCount
+		}
+		changes = append(changes, change)
+	}
+
+	for _, tag := range new.Tags {
+		if !slices.Contains(old.Tags, tag) && !slices.Contains(input.AddTags, tag) {
+			input.AddTags = append(input.AddTags, tag)
+			changes = append(changes, Change{Field: FieldTags, Name: tag, New: tag})
+		}
+	}
+	for _, tag := range old.Tags {
+		if cfg.clearEmpty && !slices.Contains(new.Tags, tag) && !slices.Contains(input.RemoveTags, tag) {
+			input.RemoveTags = append(input.RemoveTags, tag)
+			changes = append(changes, Change{Field: FieldTags, Name: tag, Old: tag})
+		}
+	}
+
+	for _, attr := range new.Attributes {
+		i := slices.IndexFunc(old.Attributes, func(a Attribute) bool { return a.Name == attr.Name })
+		if i >= 0 && attributeEqual(old.Attributes[i], attr) {
+			continue
+		}
+
+		change := Change{Field: FieldAttributes, Name: attr.Name, New: attr.Value}
+		if i >= 0 {
+			change.Old = old.Attributes[i].Value
+		}
+		input.SetAttributes = append(input.SetAttributes, attr)
+		changes = append(changes, change)
+	}
+	for _, attr := range old.Attributes {
+		if cfg.clearEmpty && !slices.ContainsFunc(new.Attributes, func(a Attribute) bool { return a.Name == attr.Name }) {
+			input.DeleteAttributes = append(input.DeleteAttributes, attr.Name)
+			changes = append(changes, Change{Field: FieldAttributes, Name: attr.Name, Old: attr.Value})
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return &input, changes
+}
+
+func ptrEqual[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,136 @@
+package leadsdb_test
+
+import (
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+)
+
+func TestDiff(t *testing.T) {
+	old := &leadsdb.Lead{
+		Name:       "Acme",
+		Source:     "maps",
+		City:       "Berlin",
+		Phone:      "123",
+		Rating:     leadsdb.Ptr(4.0),
+		Tags:       []string{"a", "b"},
+		Attributes: []leadsdb.Attribute{leadsdb.NumberAttr("employees", 10), leadsdb.TextAttr("zip", "10115")},
+	}
+
+	if input := leadsdb.Diff(old, old); input != nil {
+		t.Errorf("Diff of identical leads = %+v, want nil", input)
+	}
+
+	reordered := *old
+	reordered.Tags = []string{"b", "a"}
+	if input := leadsdb.Diff(old, &reordered); input != nil {
+		t.Errorf("Diff after reordering tags = %+v, want nil", input)
+	}
+
+	updated := &leadsdb.Lead{
+		Source:     "maps",
+		City:       "Munich",
+		Rating:     leadsdb.Ptr(4.5),
+		Tags:       []string{"b", "c"},
+		Attributes: []leadsdb.Attribute{leadsdb.NumberAttr("employees", 20)},
+	}
+
+	want := []string{
+		`city: "Berlin" -> "Munich"`,
+		`phone: "123" -> (none)`,
+		`rating: 4 -> 4.5`,
+		`tags: added "c"`,
+		`tags: removed "a"`,
+		`attributes.employees: 10 -> 20`,
+		`attributes.zip: "10115" -> (none)`,
+	}
+	changes := leadsdb.Changes(old, updated, leadsdb.ClearEmpty())
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes %v, want %d", len(changes), changes, len(want))
+	}
+	for i, change := range changes {
+		if got := change.String(); got != want[i] {
+			t.Errorf("change %d = %s, want %s", i, got, want[i])
+		}
+	}
+
+	input := leadsdb.Diff(old, updated, leadsdb.ClearEmpty())
+	if input.Name != nil {
+		t.Errorf("empty Name should be left unchanged, got %q", *input.Name)
+	}
+	if len(input.Clear) != 1 || input.Clear[0] != leadsdb.FieldPhone {
+		t.Errorf("Clear = %v, want [phone]", input.Clear)
+	}
+}
+
+func TestDiffNilLeads(t *testing.T) {
+	if input := leadsdb.Diff(nil, nil); input != nil {
+		t.Errorf("Diff(nil, nil) = %+v, want nil", input)
+	}
+
+	input := leadsdb.Diff(nil, &leadsdb.Lead{City: "Berlin"})
+	if input == nil || input.City == nil || *input.City != "Berlin" {
+		t.Errorf("Diff(nil, lead) = %+v, want city set", input)
+	}
+
+	if input = leadsdb.Diff(&leadsdb.Lead{City: "Berlin"}, nil); input != nil {
+		t.Errorf("Diff(lead, nil) = %+v, want nil", input)
+	}
+
+	input = leadsdb.Diff(&leadsdb.Lead{City: "Berlin"}, nil, leadsdb.ClearEmpty())
+	if input == nil || len(input.Clear) != 1 || input.Clear[0] != leadsdb.FieldCity {
+		t.Errorf("Diff(lead, nil, ClearEmpty()) = %+v, want city cleared", input)
+	}
+}
+
+func TestDiffPartialLead(t *testing.T) {
+	stored := &leadsdb.Lead{
+		Name:        "Acme",
+		Source:      "maps",
+		City:        "Berlin",
+		Phone:       "123",
+		Email:       "info@acme.test",
+		Rating:      leadsdb.Ptr(4.0),
+		ReviewCount: leadsdb.Ptr(10),
+		Coordinates: &leadsdb.Coordinate{Latitude: 52.52, Longitude: 13.405},
+		Tags:        []string{"saas", "vip"},
+		Attributes:  []leadsdb.Attribute{leadsdb.NumberAttr("employees", 10), leadsdb.TextAttr("zip", "10115")},
+	}
+	// A re-scrape that only found some of the fields.
+	scraped := &leadsdb.Lead{
+		Name:       "Acme",
+		Source:     "maps",
+		Phone:      "456",
+		Rating:     leadsdb.Ptr(4.5),
+		Tags:       []string{"saas", "b2b"},
+		Attributes: []leadsdb.Attribute{leadsdb.NumberAttr("employees", 20)},
+	}
+
+	want := []string{
+		`phone: "123" -> "456"`,
+		`rating: 4 -> 4.5`,
+		`tags: added "b2b"`,
+		`attributes.employees: 10 -> 20`,
+	}
+	changes := leadsdb.Changes(stored, scraped)
+	if len(changes) != len(want) {
+		t.Fatalf("got changes %v, want %v", changes, want)
+	}
+	for i, change := range changes {
+		if got := change.String(); got != want[i] {
+			t.Errorf("change %d = %s, want %s", i, got, want[i])
+		}
+	}
+
+	input := leadsdb.Diff(stored, scraped)
+	if len(input.Clear) != 0 || len(input.RemoveTags) != 0 || len(input.DeleteAttributes) != 0 {
+		t.Errorf("Diff removed data missing from the partial lead: %+v", input)
+	}
+	if input.City != nil || input.Email != nil || input.ReviewCount != nil || input.Coordinates != nil {
+		t.Errorf("Diff set fields the partial lead does not have: %+v", input)
+	}
+
+	if input := leadsdb.Diff(stored, &leadsdb.Lead{Name: "Acme", Source: "maps"}); input != nil {
+		t.Errorf("Diff against a lead with only required fields = %+v, want nil", input)
+	}
+}
@@ -0,0 +1,812 @@
+package leadsdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParseError describes a syntax error in a filter expression.
+type ParseError struct {
+	// Pos is the byte offset in the input where the error was detected.
+	Pos int
+	// Message describes the problem.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("leadsdb: filter syntax error at position %d: %s", e.Pos, e.Message)
+}
+
+// ParseFilters parses a filter expression into list options.
+//
+// Comparisons are combined with "and", "or" and "not" (in decreasing order of
+// precedence: not, and, or) and can be grouped with parentheses:
+//
+//	city = "Berlin" and rating >= 4.5 and tags contains "saas" and attr:employees > 50
+//	(city = "Berlin" or city = "Paris") and not email is empty
+//	location within_radius(52.52, 13.405, 50)
+//
+// A plain conjunction of comparisons yields one FilterOption per comparison;
+// anything else that cannot be expressed with AND/OR filters yields filter groups.
+func ParseFilters(s string) ([]ListOption, error) {
+	p := &parser{lex: lexer{input: s}}
+	p.next()
+
+	if p.tok.kind == tokEOF {
+		return nil, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	return root.options(), nil
+}
+
+// FormatFilters renders the filters and filter groups in opts as a filter
+// expression accepted by ParseFilters. Options other than filters are ignored.
+func FormatFilters(opts ...ListOption) string {
+	cfg := &listConfig{}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+
+	if len(cfg.filters) == 0 && len(cfg.groups) == 1 {
+		return cfg.groups[0].format(precOr)
+	}
+
+	var ands, ors []string
+	for _, f := range cfg.filters {
+		if f.logic == logicOr {
+			ors = append(ors, formatFilter(f))
+			continue
+		}
+		ands = append(ands, formatFilter(f))
+	}
+
+	switch {
+	case len(ors) == 1:
+		ands = append(ands, ors[0])
+	case len(ors) > 1 && len(ands) == 0 && len(cfg.groups) == 0:
+		return strings.Join(ors, " or ")
+	case len(ors) > 1:
+		ands = append(ands, "("+strings.Join(ors, " or ")+")")
+	}
+
+	for _, g := range cfg.groups {
+		if s := g.format(precAnd); s != "" {
+			ands = append(ands, s)
+		}
+	}
+
+	return strings.Join(ands, " and ")
+}
+
+// Operator precedence used when formatting groups.
+const (
+	precOr = iota
+	precAnd
+	precNot
+)
+
+func (n node) format(parent int) string {
+	var prec int
+	var sep string
+	switch n.Op {
+	case "":
+		return formatFilter(filter{operator: n.Operator, field: n.Field, value: n.Value})
+	case groupNot:
+		if len(n.Conditions) != 1 {
+			return ""
+		}
+		return "not " + n.Conditions[0].format(precNot)
+	case groupAll:
+		prec, sep = precAnd, " and "
+	default:
+		prec, sep = precOr, " or "
+	}
+
+	parts := make([]string, 0, len(n.Conditions))
+	for _, c := range n.Conditions {
+		if s := c.format(prec); s != "" {
+			parts = append(parts, s)
+		}
+	}
+
+	s := strings.Join(parts, sep)
+	if len(parts) > 1 && parent > prec {
+		s = "(" + s + ")"
+	}
+	return s
+}
+
+func formatFilter(f filter) string {
+	field := f.field
+	if name, ok := strings.CutPrefix(field, "attr:"); ok && !isIdent(name) {
+		field = "attr:" + strconv.Quote(name)
+	}
+
+	switch f.operator {
+	case "is_empty", "array_empty":
+		return field + " is empty"
+	case "is_not_empty", "array_not_empty":
+		return field + " is not empty"
+	case "is_set":
+		return field + " is set"
+	case "is_not_set":
+		return field + " is not set"
+	case "within_radius":
+		return field + " within_radius(" + strings.ReplaceAll(f.value, ",", ", ") + ")"
+	case "contains", "array_contains":
+		return field + " contains " + strconv.Quote(f.value)
+	case "not_contains", "array_not_contains":
+		return field + " not contains " + strconv.Quote(f.value)
+	}
+
+	value := strconv.Quote(f.value)
+	if isNumberField(f.field) {
+		value = f.value
+	} else if strings.HasPrefix(f.field, "attr:") {
+		// Only values that parse back to the same text are written as numbers,
+		// so that text such as "01234" keeps its leading zero.
+		if n, err := strconv.ParseFloat(f.value, 64); err == nil && formatNumber(n) == f.value {
+			value = f.value
+		}
+	}
+
+	return field + " " + comparisonSymbols[f.operator] + " " + value
+}
+
+var comparisonSymbols = map[string]string{
+	"eq":  "=",
+	"neq": "!=",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+func isNumberField(field string) bool {
+	return field == "rating" || field == "review_count"
+}
+
+func isTextField(field string) bool {
+	switch field {
+	case "name", "city", "country", "state", "category", "source", "source_id", "email", "phone", "website":
+		return true
+	default:
+		return false
+	}
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !isIdentRune(r, i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentRune(r rune, first bool) bool {
+	if r == '_' || unicode.IsLetter(r) {
+		return true
+	}
+	return !first && (r == '-' || unicode.IsDigit(r))
+}
+
+// expr is a node of a parsed filter expression.
+type expr struct {
+	op       groupOp
+	children []*expr
+	leaf     FilterOption
+}
+
+// options converts the expression into list options, preferring plain
+// AND/OR filters and falling back to groups where they are not expressive enough.
+func (e *expr) options() []ListOption {
+	switch e.op {
+	case "":
+		return []ListOption{e.leaf}
+	case groupAny:
+		if e.allLeaves() {
+			return e.orFilters()
+		}
+		return []ListOption{e.group()}
+	case groupNot:
+		return []ListOption{e.group()}
+	}
+
+	// Only a single set of OR filters can be expressed without a group.
+	var opts []ListOption
+	hasOr := false
+	for _, c := range e.children {
+		switch {
+		case c.op == "":
+			opts = append(opts, c.leaf)
+		case c.op == groupAny && c.allLeaves() && !hasOr:
+			hasOr = true
+			opts = append(opts, c.orFilters()...)
+		default:
+			opts = append(opts, c.group())
+		}
+	}
+
+	return opts
+}
+
+func (e *expr) allLeaves() bool {
+	for _, c := range e.children {
+		if c.op != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *expr) orFilters() []ListOption {
+	opts := make([]ListOption, 0, len(e.children))
+	for _, c := range e.children {
+		f := c.leaf
+		f.filter.logic = logicOr
+		opts = append(opts, f)
+	}
+	return opts
+}
+
+func (e *expr) condition() Condition {
+	if e.op == "" {
+		return e.leaf
+	}
+	return e.group()
+}
+
+func (e *expr) group() FilterGroup {
+	conds := make([]Condition, 0, len(e.children))
+	for _, c := range e.children {
+		conds = append(conds, c.condition())
+	}
+
+	switch e.op {
+	case groupNot:
+		return Not(conds[0])
+	case groupAny:
+		return AnyOf(conds...)
+	default:
+		return AllOf(conds...)
+	}
+}
+
+type parser struct {
+	lex lexer
+	tok token
+}
+
+func (p *parser) next() {
+	p.tok = p.lex.next()
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &ParseError{Pos: p.tok.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// keyword reports whether the current token is the given case-insensitive keyword.
+func (p *parser) keyword(kw string) bool {
+	return p.tok.kind == tokIdent && strings.EqualFold(p.tok.text, kw)
+}
+
+func (p *parser) expectKeyword(kw string) error {
+	if !p.keyword(kw) {
+		return p.errorf("expected %q, got %s", kw, p.tok)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) expectSymbol(sym string) error {
+	if p.tok.kind != tokSymbol || p.tok.text != sym {
+		return p.errorf("expected %q, got %s", sym, p.tok)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseOr() (*expr, error) {
+	return p.parseChain(groupAny, "or", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (*expr, error) {
+	return p.parseChain(groupAll, "and", p.parseUnary)
+}
+
+func (p *parser) parseChain(op groupOp, kw string, operand func() (*expr, error)) (*expr, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	if !p.keyword(kw) {
+		return first, nil
+	}
+
+	e := &expr{op: op}
+	e.add(first)
+	for p.keyword(kw) {
+		p.next()
+		c, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		e.add(c)
+	}
+
+	return e, nil
+}
+
+// add appends c to e, flattening nested chains of the same operator.
+func (e *expr) add(c *expr) {
+	if c.op == e.op {
+		e.children = append(e.children, c.children...)
+		return
+	}
+	e.children = append(e.children, c)
+}
+
+func (p *parser) parseUnary() (*expr, error) {
+	switch {
+	case p.keyword("not"):
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &expr{op: groupNot, children: []*expr{operand}}, nil
+	case p.tok.kind == tokSymbol && p.tok.text == "(":
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	default:
+		f, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		return &expr{leaf: f}, nil
+	}
+}
+
+func (p *parser) parseComparison() (FilterOption, error) {
+	if p.tok.kind != tokIdent {
+		return FilterOption{}, p.errorf("expected field, got %s", p.tok)
+	}
+
+	start := p.tok
+	field := strings.ToLower(start.text)
+	p.next()
+
+	switch {
+	case field == "attr":
+		if err := p.expectSymbol(":"); err != nil {
+			return FilterOption{}, err
+		}
+		if p.tok.kind != tokIdent && p.tok.kind != tokString {
+			return FilterOption{}, p.errorf("expected attribute name, got %s", p.tok)
+		}
+		name := p.tok.text
+		p.next()
+		return p.parseAttr(Attr(name))
+	case field == "location":
+		return p.parseLocation(Location())
+	case field == "tags":
+		return p.parseArray(Tags())
+	case isNumberField(field):
+		return p.parseNumber(&NumberField{logic: logicAnd, field: field})
+	case isTextField(field):
+		return p.parseText(&TextField{logic: logicAnd, field: field})
+	default:
+		return FilterOption{}, &ParseError{Pos: start.pos, Message: fmt.Sprintf("unknown field %q", start.text)}
+	}
+}
+
+// parseEmptiness parses "is empty" and "is not empty" after the "is" keyword.
+func (p *parser) parseEmptiness() (empty bool, err error) {
+	negated := p.keyword("not")
+	if negated {
+		p.next()
+	}
+	if err := p.expectKeyword("empty"); err != nil {
+		return false, err
+	}
+	return !negated, nil
+}
+
+func (p *parser) parseText(f *TextField) (FilterOption, error) {
+	if p.keyword("is") {
+		p.next()
+		empty, err := p.parseEmptiness()
+		if err != nil {
+			return FilterOption{}, err
+		}
+		if empty {
+			return f.IsEmpty(), nil
+		}
+		return f.IsNotEmpty(), nil
+	}
+
+	op, err := p.parseOperator("=", "!=", "contains", "not contains")
+	if err != nil {
+		return FilterOption{}, err
+	}
+	value, err := p.parseString()
+	if err != nil {
+		return FilterOption{}, err
+	}
+
+	switch op {
+	case "=":
+		return f.Eq(value), nil
+	case "!=":
+		return f.Neq(value), nil
+	case "contains":
+		return f.Contains(value), nil
+	default:
+		return f.NotContains(value), nil
+	}
+}
+
+func (p *parser) parseNumber(f *NumberField) (FilterOption, error) {
+	op, err := p.parseOperator("=", "!=", ">", ">=", "<", "<=")
+	if err != nil {
+		return FilterOption{}, err
+	}
+	value, err := p.parseNumberValue()
+	if err != nil {
+		return FilterOption{}, err
+	}
+
+	switch op {
+	case "=":
+		return f.Eq(value), nil
+	case "!=":
+		return f.Neq(value), nil
+	case ">":
+		return f.Gt(value), nil
+	case ">=":
+		return f.Gte(value), nil
+	case "<":
+		return f.Lt(value), nil
+	default:
+		return f.Lte(value), nil
+	}
+}
+
+func (p *parser) parseArray(f *ArrayField) (FilterOption, error) {
+	if p.keyword("is") {
+		p.next()
+		empty, err := p.parseEmptiness()
+		if err != nil {
+			return FilterOption{}, err
+		}
+		if empty {
+			return f.IsEmpty(), nil
+		}
+		return f.IsNotEmpty(), nil
+	}
+
+	op, err := p.parseOperator("contains", "not contains")
+	if err != nil {
+		return FilterOption{}, err
+	}
+	value, err := p.parseString()
+	if err != nil {
+		return FilterOption{}, err
+	}
+
+	if op == "contains" {
+		return f.Contains(value), nil
+	}
+	return f.NotContains(value), nil
+}
+
+func (p *parser) parseLocation(f *LocationField) (FilterOption, error) {
+	if p.keyword("is") {
+		p.next()
+		negated := p.keyword("not")
+		if negated {
+			p.next()
+		}
+		if err := p.expectKeyword("set"); err != nil {
+			return FilterOption{}, err
+		}
+		if negated {
+			return f.IsNotSet(), nil
+		}
+		return f.IsSet(), nil
+	}
+
+	if err := p.expectKeyword("within_radius"); err != nil {
+		return FilterOption{}, err
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return FilterOption{}, err
+	}
+
+	var args [3]float64
+	for i := range args {
+		if i > 0 {
+			if err := p.expectSymbol(","); err != nil {
+				return FilterOption{}, err
+			}
+		}
+		v, err := p.parseNumberValue()
+		if err != nil {
+			return FilterOption{}, err
+		}
+		args[i] = v
+	}
+
+	if err := p.expectSymbol(")"); err != nil {
+		return FilterOption{}, err
+	}
+
+	return f.WithinRadius(args[0], args[1], args[2]), nil
+}
+
+func (p *parser) parseAttr(f *AttrField) (FilterOption, error) {
+	op, err := p.parseOperator("=", "!=", ">", ">=", "<", "<=", "contains")
+	if err != nil {
+		return FilterOption{}, err
+	}
+
+	switch op {
+	case "=", "!=":
+		if p.tok.kind == tokNumber {
+			value, err := p.parseNumberValue()
+			if err != nil {
+				return FilterOption{}, err
+			}
+			if op == "=" {
+				return f.EqNumber(value), nil
+			}
+			return f.Neq(formatNumber(value)), nil
+		}
+		value, err := p.parseString()
+		if err != nil {
+			return FilterOption{}, err
+		}
+		if op == "=" {
+			return f.Eq(value), nil
+		}
+		return f.Neq(value), nil
+	case "contains":
+		value, err := p.parseString()
+		if err != nil {
+			return FilterOption{}, err
+		}
+		return f.Contains(value), nil
+	}
+
+	value, err := p.parseNumberValue()
+	if err != nil {
+		return FilterOption{}, err
+	}
+
+	switch op {
+	case ">":
+		return f.Gt(value), nil
+	case ">=":
+		return f.Gte(value), nil
+	case "<":
+		return f.Lt(value), nil
+	default:
+		return f.Lte(value), nil
+	}
+}
+
+// parseOperator consumes one of the allowed comparison operators.
+// Keyword operators may span two words, such as "not contains".
+func (p *parser) parseOperator(allowed ...string) (string, error) {
+	var op string
+	switch {
+	case p.tok.kind == tokSymbol:
+		op = p.tok.text
+	case p.keyword("contains"):
+		op = "contains"
+	case p.keyword("not"):
+		start := p.tok
+		p.next()
+		if !p.keyword("contains") {
+			return "", p.errorf("expected \"contains\" after \"not\", got %s", p.tok)
+		}
+		p.tok.pos = start.pos
+		op = "not contains"
+	}
+
+	for _, a := range allowed {
+		if op == a {
+			p.next()
+			return op, nil
+		}
+	}
+
+	return "", p.errorf("expected one of %s, got %s", strings.Join(quoteAll(allowed), ", "), p.tok)
+}
+
+func (p *parser) parseString() (string, error) {
+	if p.tok.kind != tokString {
+		return "", p.errorf("expected string, got %s", p.tok)
+	}
+	s := p.tok.text
+	p.next()
+	return s, nil
+}
+
+func (p *parser) parseNumberValue() (float64, error) {
+	if p.tok.kind != tokNumber {
+		return 0, p.errorf("expected number, got %s", p.tok)
+	}
+	v, err := strconv.ParseFloat(p.tok.text, 64)
+	if err != nil {
+		return 0, p.errorf("invalid number %q", p.tok.text)
+	}
+	p.next()
+	return v, nil
+}
+
+func quoteAll(ss []string) []string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = strconv.Quote(s)
+	}
+	return out
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokSymbol
+	tokInvalid
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of input"
+	case tokString:
+		return strconv.Quote(t.text)
+	case tokInvalid:
+		return t.text
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+type lexer struct {
+	input string
+	pos   int
+}
+
+func (l *lexer) next() token {
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.pos += size
+	}
+
+	start := l.pos
+	if start >= len(l.input) {
+		return token{kind: tokEOF, pos: start}
+	}
+
+	c := l.input[start]
+	switch {
+	case c == '"':
+		return l.lexString()
+	case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.lexNumber()
+	case strings.ContainsRune("(),:", rune(c)):
+		l.pos++
+		return token{kind: tokSymbol, text: string(c), pos: start}
+	case c == '=':
+		l.pos++
+		return token{kind: tokSymbol, text: "=", pos: start}
+	case c == '!' || c == '<' || c == '>':
+		l.pos++
+		if l.pos < len(l.input) && l.input[l.pos] == '=' {
+			l.pos++
+			return token{kind: tokSymbol, text: l.input[start:l.pos], pos: start}
+		}
+		if c == '!' {
+			return token{kind: tokInvalid, text: `"!" (did you mean "!="?)`, pos: start}
+		}
+		return token{kind: tokSymbol, text: string(c), pos: start}
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.input[start:])
+	if !isIdentRune(r, true) {
+		_, size := utf8.DecodeRuneInString(l.input[start:])
+		l.pos += size
+		return token{kind: tokInvalid, text: fmt.Sprintf("invalid character %q", r), pos: start}
+	}
+
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if !isIdentRune(r, false) {
+			break
+		}
+		l.pos += size
+	}
+
+	return token{kind: tokIdent, text: l.input[start:l.pos], pos: start}
+}
+
+func (l *lexer) lexString() token {
+	start := l.pos
+	l.pos++
+
+	for l.pos < len(l.input) {
+		switch l.input[l.pos] {
+		case '\\':
+			l.pos += 2
+		case '"':
+			l.pos++
+			s, err := strconv.Unquote(l.input[start:l.pos])
+			if err != nil {
+				return token{kind: tokInvalid, text: "invalid string " + l.input[start:l.pos], pos: start}
+			}
+			return token{kind: tokString, text: s, pos: start}
+		default:
+			l.pos++
+		}
+	}
+
+	l.pos = len(l.input)
+	return token{kind: tokInvalid, text: "unterminated string", pos: start}
+}
+
+func (l *lexer) lexNumber() token {
+	start := l.pos
+	if l.input[l.pos] == '-' {
+		l.pos++
+	}
+
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		if (c < '0' || c > '9') && c != '.' && c != 'e' && c != 'E' {
+			break
+		}
+		if (c == 'e' || c == 'E') && l.pos+1 < len(l.input) && (l.input[l.pos+1] == '-' || l.input[l.pos+1] == '+') {
+			l.pos++
+		}
+		l.pos++
+	}
+
+	return token{kind: tokNumber, text: l.input[start:l.pos], pos: start}
+}
@@ -0,0 +1,83 @@
+package leadsdb_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+)
+
+func TestFormatFiltersRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []leadsdb.ListOption
+		want string
+	}{
+		{"text", []leadsdb.ListOption{leadsdb.City().Eq("Berlin")}, `city = "Berlin"`},
+		{"number", []leadsdb.ListOption{leadsdb.Rating().Gte(4.5)}, `rating >= 4.5`},
+		{"attr number", []leadsdb.ListOption{leadsdb.Attr("employees").EqNumber(50)}, `attr:employees = 50`},
+		{"attr text with leading zero", []leadsdb.ListOption{leadsdb.Attr("zip").Eq("01234")}, `attr:zip = "01234"`},
+		{"attr text in exponent form", []leadsdb.ListOption{leadsdb.Attr("code").Eq("1e3")}, `attr:code = "1e3"`},
+		{"attr text with trailing zero", []leadsdb.ListOption{leadsdb.Attr("version").Neq("1.50")}, `attr:version != "1.50"`},
+		{"attr numeric text", []leadsdb.ListOption{leadsdb.Attr("floor").Eq("3")}, `attr:floor = 3`},
+		{"tags", []leadsdb.ListOption{leadsdb.Tags().Contains("saas")}, `tags contains "saas"`},
+		{"quotes", []leadsdb.ListOption{leadsdb.Name().Eq(`Joe's "Diner"`)}, `name = "Joe's \"Diner\""`},
+		{"radius", []leadsdb.ListOption{leadsdb.Location().WithinRadius(52.52, 13.405, 50)}, `location within_radius(52.52, 13.405, 50)`},
+		{"or", []leadsdb.ListOption{leadsdb.City().Eq("Berlin"), leadsdb.Or().City().Eq("Paris")}, ""},
+		{"group", []leadsdb.ListOption{leadsdb.AnyOf(leadsdb.City().Eq("Berlin"), leadsdb.Not(leadsdb.Email().IsEmpty()))}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := leadsdb.FormatFilters(tt.opts...)
+			if tt.want != "" && text != tt.want {
+				t.Fatalf("FormatFilters = %s, want %s", text, tt.want)
+			}
+
+			parsed, err := leadsdb.ParseFilters(text)
+			if err != nil {
+				t.Fatalf("ParseFilters(%s): %v", text, err)
+			}
+			if again := leadsdb.FormatFilters(parsed...); again != text {
+				t.Fatalf("round trip changed %s into %s", text, again)
+			}
+		})
+	}
+}
+
+func TestParseFiltersErrorPosition(t *testing.T) {
+	tests := []struct {
+		input   string
+		pos     int
+		message string
+	}{
+		{`bogus = "x"`, 0, `unknown field "bogus"`},
+		{`city ~ "a"`, 5, "expected one of"},
+		{`city = `, 7, "expected string, got end of input"},
+		{`city = "unterminated`, 7, "unterminated string"},
+		{`rating >= abc`, 10, `expected number, got "abc"`},
+		{`tags = "x"`, 5, `expected one of "contains", "not contains"`},
+		{`attr: = 1`, 6, "expected attribute name"},
+		{`city = "Berlin" and`, 19, "expected field, got end of input"},
+		{`(city = "a"`, 11, `expected ")"`},
+		{`city = "a" city = "b"`, 11, `unexpected "city"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := leadsdb.ParseFilters(tt.input)
+
+			var parseErr *leadsdb.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got %v, want a *ParseError", err)
+			}
+			if parseErr.Pos != tt.pos {
+				t.Errorf("Pos = %d, want %d", parseErr.Pos, tt.pos)
+			}
+			if !strings.Contains(parseErr.Message, tt.message) {
+				t.Errorf("Message = %q, want it to contain %q", parseErr.Message, tt.message)
+			}
+		})
+	}
+}
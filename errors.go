@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // Sentinel errors for common API error cases.
@@ -13,6 +15,11 @@ var (
 	ErrRateLimited  = errors.New("leadsdb: rate limited")
 	ErrForbidden    = errors.New("leadsdb: forbidden")
 	ErrInternal     = errors.New("leadsdb: internal server error")
+	ErrCircuitOpen  = errors.New("leadsdb: circuit breaker open")
+	ErrValidation   = errors.New("leadsdb: validation failed")
+	// ErrConflict is returned for 409 Conflict and 412 Precondition Failed,
+	// for example when an update made with IfMatch finds the lead changed.
+	ErrConflict = errors.New("leadsdb: conflict")
 )
 
 // APIError represents an error response from the LeadsDB API.
@@ -20,16 +27,30 @@ type APIError struct {
 	StatusCode int    `json:"-"`
 	Code       string `json:"code"`
 	Message    string `json:"message"`
-	RetryAfter int    `json:"-"` // seconds, from Retry-After header (for 429)
+	// RequestID identifies the request to LeadsDB support. It is taken from the
+	// X-Request-Id response header, or the error body when the header is missing.
+	RequestID string `json:"request_id"`
+	// RetryDelay is the delay requested by the Retry-After header of a 429 or
+	// 5xx response, or zero.
+	RetryDelay time.Duration `json:"-"`
+	// RetryAfter is RetryDelay in whole seconds, rounded up.
+	//
+	// Deprecated: Use RetryDelay, which keeps the full precision.
+	RetryAfter int `json:"-"`
 }
 
 // Error implements the error interface.
 func (e *APIError) Error() string {
+	status := fmt.Sprintf("status %d", e.StatusCode)
+	if e.RequestID != "" {
+		status += ", request " + e.RequestID
+	}
+
 	if e.Code != "" {
-		return fmt.Sprintf("leadsdb: %s: %s (status %d)", e.Code, e.Message, e.StatusCode)
+		return fmt.Sprintf("leadsdb: %s: %s (%s)", e.Code, e.Message, status)
 	}
 
-	return fmt.Sprintf("leadsdb: %s (status %d)", e.Message, e.StatusCode)
+	return fmt.Sprintf("leadsdb: %s (%s)", e.Message, status)
 }
 
 // Is implements errors.Is support for sentinel errors.
@@ -41,6 +62,10 @@ func (e *APIError) Is(target error) bool {
 		return target == ErrForbidden
 	case http.StatusNotFound:
 		return target == ErrNotFound
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return target == ErrValidation
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return target == ErrConflict
 	case http.StatusTooManyRequests:
 		return target == ErrRateLimited
 	case http.StatusInternalServerError:
@@ -49,3 +74,42 @@ func (e *APIError) Is(target error) bool {
 		return false
 	}
 }
+
+// FieldError describes why a single field of a request was rejected.
+type FieldError struct {
+	// Field is the name of the field, for example "email".
+	Field string `json:"field"`
+	// Rule is the validation rule that failed, for example "required".
+	Rule string `json:"rule"`
+	// Message is a human-readable description of the problem.
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when the API rejects a request as invalid with
+// 400 Bad Request or 422 Unprocessable Entity. It matches ErrValidation, and
+// errors.As also finds the underlying *APIError.
+type ValidationError struct {
+	*APIError
+	// Fields lists the rejected fields from the "details" array of the error
+	// response, when the API reports them.
+	Fields []FieldError
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.APIError.Error()
+	}
+
+	details := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		details[i] = f.Field + ": " + f.Message
+	}
+
+	return e.APIError.Error() + ": " + strings.Join(details, "; ")
+}
+
+// Unwrap returns the underlying *APIError.
+func (e *ValidationError) Unwrap() error {
+	return e.APIError
+}
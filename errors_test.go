@@ -0,0 +1,102 @@
+package leadsdb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestAPIErrorRetryAfter(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	srv.InjectFault(leadsdbtest.Fault{Status: http.StatusTooManyRequests, Code: "rate_limited", RetryAfter: "2"})
+
+	_, err := srv.Client(leadsdb.WithMaxRetries(1)).List(context.Background())
+
+	var apiErr *leadsdb.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %v, want an *APIError", err)
+	}
+	if !errors.Is(err, leadsdb.ErrRateLimited) {
+		t.Errorf("error does not match ErrRateLimited")
+	}
+	if apiErr.RetryDelay != 2*time.Second {
+		t.Errorf("RetryDelay = %v, want 2s", apiErr.RetryDelay)
+	}
+	if apiErr.RetryAfter != 2 {
+		t.Errorf("RetryAfter = %d, want 2 seconds", apiErr.RetryAfter)
+	}
+}
+
+func TestValidationErrorFields(t *testing.T) {
+	t.Run("fake server", func(t *testing.T) {
+		srv := leadsdbtest.NewServer()
+		defer srv.Close()
+
+		lead := srv.AddLead(leadsdb.Lead{Name: "Acme", Source: "test"})
+		_, err := srv.Client().Update(context.Background(), lead.ID, &leadsdb.UpdateLeadInput{Name: leadsdb.Ptr(" ")})
+
+		var valErr *leadsdb.ValidationError
+		if !errors.As(err, &valErr) {
+			t.Fatalf("got %v, want a *ValidationError", err)
+		}
+		if len(valErr.Fields) != 1 || valErr.Fields[0].Field != "name" || valErr.Fields[0].Rule != "required" {
+			t.Fatalf("got fields %+v, want name required", valErr.Fields)
+		}
+		if !errors.Is(err, leadsdb.ErrValidation) {
+			t.Fatal("error does not match ErrValidation")
+		}
+	})
+
+	tests := []struct {
+		status int
+		body   string
+		fields []leadsdb.FieldError
+	}{
+		{http.StatusBadRequest,
+			`{"code":"validation_error","message":"validation failed","details":[{"field":"email","rule":"email","message":"is not a valid email"}]}`,
+			[]leadsdb.FieldError{{Field: "email", Rule: "email", Message: "is not a valid email"}}},
+		{http.StatusUnprocessableEntity,
+			`{"code":"validation_error","message":"validation failed","details":[{"field":"name","rule":"required","message":"is required"},{"field":"source","rule":"required","message":"is required"}]}`,
+			[]leadsdb.FieldError{{Field: "name", Rule: "required", Message: "is required"}, {Field: "source", Rule: "required", Message: "is required"}}},
+		{http.StatusUnprocessableEntity, `{"code":"validation_error","message":"validation failed"}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-Id", "req-1")
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			client := leadsdb.New("key", leadsdb.WithBaseURL(srv.URL))
+			_, err := client.Create(context.Background(), &leadsdb.Lead{Name: "Acme", Source: "test"})
+
+			var valErr *leadsdb.ValidationError
+			if !errors.As(err, &valErr) {
+				t.Fatalf("got %v, want a *ValidationError", err)
+			}
+			if !slices.Equal(valErr.Fields, tt.fields) {
+				t.Fatalf("got fields %+v, want %+v", valErr.Fields, tt.fields)
+			}
+			if !errors.Is(err, leadsdb.ErrValidation) {
+				t.Fatal("error does not match ErrValidation")
+			}
+			var apiErr *leadsdb.APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || apiErr.RequestID != "req-1" {
+				t.Fatalf("got %+v, want the underlying APIError with status %d", apiErr, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,539 @@
+package leadsdb
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat defines the format for exporting leads.
+type ExportFormat string
+
+const (
+	ExportCSV  ExportFormat = "csv"
+	ExportJSON ExportFormat = "json"
+)
+
+// contentType returns the media type requested in the Accept header for format.
+func (f ExportFormat) contentType() string {
+	switch f {
+	case ExportCSV:
+		return "text/csv"
+	case ExportJSON:
+		return "application/json"
+	default:
+		return "*/*"
+	}
+}
+
+// Export exports leads in the specified format and returns a reader.
+// Filter and sort options restrict and order the exported leads the same way
+// as for List; Limit and Cursor are ignored.
+//
+// The request is retried like any other call. When the download fails
+// part way through, the reader requests the rest of the export with an HTTP
+// Range request and continues where it stopped, up to the client's retry limit.
+// When the server answers with the whole export instead, the download only
+// continues if the export's ETag shows that it has not changed.
+// The caller must close the reader when done.
+func (c *Client) Export(ctx context.Context, format ExportFormat, opts ...ListOption) (io.ReadCloser, error) {
+	if format == "" {
+		format = ExportCSV
+	}
+
+	cfg := &listConfig{}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+
+	params := url.Values{}
+	params.Set("format", string(format))
+	if err := cfg.setQueryParams(params); err != nil {
+		return nil, err
+	}
+
+	r := &exportReader{
+		c:      c,
+		ctx:    ctx,
+		path:   "/leads/export?" + params.Encode(),
+		header: http.Header{"Accept": {format.contentType()}},
+	}
+
+	resp, err := c.send(ctx, http.MethodPost, r.path, nil, r.header, true)
+	if err != nil {
+		return nil, err
+	}
+
+	r.body = resp.Body
+	r.etag = resp.Header.Get("ETag")
+
+	return r, nil
+}
+
+// exportReader reads an export, resuming the download when it fails part way through.
+type exportReader struct {
+	c      *Client
+	ctx    context.Context
+	path   string
+	header http.Header
+
+	body    io.ReadCloser
+	etag    string
+	offset  int64
+	resumes int
+	// err is the error that ended the export, returned by every later Read.
+	err error
+}
+
+func (r *exportReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	for {
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+
+		if err == nil || errors.Is(err, io.EOF) {
+			return n, err
+		}
+		if err := r.resume(err); err != nil {
+			r.err = err
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *exportReader) Close() error {
+	return r.body.Close()
+}
+
+// resume replaces the failed body with a response starting at the current offset.
+// It returns an error wrapping cause when the export cannot be resumed.
+func (r *exportReader) resume(cause error) error {
+	r.body.Close()
+
+	for r.resumes < r.c.maxRetries {
+		if r.ctx.Err() != nil {
+			break
+		}
+		r.resumes++
+
+		header := r.header.Clone()
+		header.Set("Range", "bytes="+strconv.FormatInt(r.offset, 10)+"-")
+		if r.etag != "" {
+			header.Set("If-Range", r.etag)
+		}
+
+		resp, err := r.c.send(r.ctx, http.MethodPost, r.path, nil, header, true)
+		if err != nil {
+			return err
+		}
+
+		if r.etag != "" && resp.Header.Get("ETag") != r.etag {
+			resp.Body.Close()
+			return fmt.Errorf("leadsdb: export changed while resuming: %w", cause)
+		}
+
+		if resp.StatusCode == http.StatusPartialContent {
+			if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != r.offset {
+				resp.Body.Close()
+				return fmt.Errorf("leadsdb: resuming export: unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+			}
+		} else if r.etag == "" {
+			// The server sent the whole export again, and without an ETag there
+			// is no telling whether it is the same export.
+			resp.Body.Close()
+			break
+		} else if _, err := io.CopyN(io.Discard, resp.Body, r.offset); err != nil {
+			// The server sent the same export again; skip what was already read.
+			resp.Body.Close()
+			cause = err
+			continue
+		}
+
+		r.body = resp.Body
+		return nil
+	}
+
+	return fmt.Errorf("leadsdb: export interrupted: %w", cause)
+}
+
+// contentRangeStart returns the first byte position of a "bytes start-end/size" Content-Range.
+func contentRangeStart(v string) (int64, bool) {
+	v, ok := strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	v, _, ok = strings.Cut(v, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(v, 10, 64)
+	return start, err == nil
+}
+
+// ExportLeads exports leads matching opts and decodes the stream into leads
+// as it is read, so memory use does not grow with the size of the export.
+// Only ExportCSV and ExportJSON can be decoded.
+// Iteration stops at the first error.
+func (c *Client) ExportLeads(ctx context.Context, format ExportFormat, opts ...ListOption) iter.Seq2[*Lead, error] {
+	return func(yield func(*Lead, error) bool) {
+		if format == "" {
+			format = ExportCSV
+		}
+		if format != ExportCSV && format != ExportJSON {
+			yield(nil, fmt.Errorf("leadsdb: cannot decode export format %q", format))
+			return
+		}
+
+		body, err := c.Export(ctx, format, opts...)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer body.Close()
+
+		for lead, err := range DecodeLeads(body, format) {
+			if !yield(lead, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// DecodeLeads decodes leads from the output of Export, for example a saved export file.
+//
+// JSON input may be an array of leads or a stream of lead objects. CSV input must
+// start with a header row; columns are matched by their JSON field names, and
+// unknown columns are ignored.
+// Iteration stops at the first error.
+func DecodeLeads(r io.Reader, format ExportFormat) iter.Seq2[*Lead, error] {
+	return func(yield func(*Lead, error) bool) {
+		var dec leadDecoder
+		switch format {
+		case ExportJSON:
+			dec = newJSONLeadDecoder(r)
+		case ExportCSV, "":
+			dec = newCSVLeadDecoder(r)
+		default:
+			yield(nil, fmt.Errorf("leadsdb: cannot decode export format %q", format))
+			return
+		}
+
+		for {
+			lead, err := dec.next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(lead, nil) {
+				return
+			}
+		}
+	}
+}
+
+type leadDecoder interface {
+	// next returns the next lead, or io.EOF when the input is exhausted.
+	next() (*Lead, error)
+}
+
+type jsonLeadDecoder struct {
+	r       *bufio.Reader
+	dec     *json.Decoder
+	array   bool
+	started bool
+}
+
+func newJSONLeadDecoder(r io.Reader) *jsonLeadDecoder {
+	br := bufio.NewReader(r)
+	return &jsonLeadDecoder{r: br, dec: json.NewDecoder(br)}
+}
+
+func (d *jsonLeadDecoder) next() (*Lead, error) {
+	if !d.started {
+		d.started = true
+		if err := d.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	if d.array && !d.dec.More() {
+		if _, err := d.dec.Token(); err != nil {
+			return nil, fmt.Errorf("leadsdb: decoding export: %w", err)
+		}
+		return nil, io.EOF
+	}
+
+	var lead Lead
+	if err := d.dec.Decode(&lead); err != nil {
+		if errors.Is(err, io.EOF) && !d.array {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("leadsdb: decoding export: %w", err)
+	}
+
+	return &lead, nil
+}
+
+// start detects whether the input is a JSON array and consumes its opening bracket.
+func (d *jsonLeadDecoder) start() error {
+	for {
+		b, err := d.r.Peek(1)
+		if err != nil {
+			return err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = d.r.ReadByte()
+			continue
+		case '[':
+			d.array = true
+			_, err := d.dec.Token()
+			return err
+		default:
+			return nil
+		}
+	}
+}
+
+type csvLeadDecoder struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVLeadDecoder(r io.Reader) *csvLeadDecoder {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	return &csvLeadDecoder{r: cr}
+}
+
+func (d *csvLeadDecoder) next() (*Lead, error) {
+	if d.columns == nil {
+		header, err := d.r.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		d.columns = make(map[string]int, len(header))
+		for i, name := range header {
+			name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+			d.columns[name] = i
+		}
+	}
+
+	record, err := d.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("leadsdb: decoding export: %w", err)
+	}
+
+	lead, err := d.decode(record)
+	if err != nil {
+		line, _ := d.r.FieldPos(0)
+		return nil, fmt.Errorf("leadsdb: decoding export: line %d: %w", line, err)
+	}
+
+	return lead, nil
+}
+
+func (d *csvLeadDecoder) decode(record []string) (*Lead, error) {
+	get := func(name string) string {
+		i, ok := d.columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	lead := &Lead{
+		ID:          get("id"),
+		Name:        get("name"),
+		Source:      get("source"),
+		Description: get("description"),
+		Address:     get("address"),
+		City:        get("city"),
+		State:       get("state"),
+		Country:     get("country"),
+		PostalCode:  get("postal_code"),
+		Phone:       get("phone"),
+		Email:       get("email"),
+		Website:     get("website"),
+		Category:    get("category"),
+		SourceID:    get("source_id"),
+		LogoURL:     get("logo_url"),
+	}
+
+	var err error
+	if lead.Coordinates, err = parseCoordinates(get("latitude"), get("longitude"), get("coordinates")); err != nil {
+		return nil, err
+	}
+	if v := get("rating"); v != "" {
+		rating, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("rating: %w", err)
+		}
+		lead.Rating = &rating
+	}
+	if v := get("review_count"); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("review_count: %w", err)
+		}
+		lead.ReviewCount = &count
+	}
+	if lead.Tags, err = parseTags(get("tags")); err != nil {
+		return nil, err
+	}
+	if lead.Attributes, err = parseAttributes(get("attributes")); err != nil {
+		return nil, err
+	}
+	if lead.CreatedAt, err = parseTimestamp(get("created_at")); err != nil {
+		return nil, fmt.Errorf("created_at: %w", err)
+	}
+	if lead.UpdatedAt, err = parseTimestamp(get("updated_at")); err != nil {
+		return nil, fmt.Errorf("updated_at: %w", err)
+	}
+
+	return lead, nil
+}
+
+// parseCoordinates reads coordinates from separate latitude and longitude
+// columns, or from a combined "lat,lon" or JSON object column.
+func parseCoordinates(lat, lon, combined string) (*Coordinate, error) {
+	if lat == "" && lon == "" && combined == "" {
+		return nil, nil
+	}
+
+	if combined != "" && lat == "" && lon == "" {
+		if strings.HasPrefix(combined, "{") {
+			var c Coordinate
+			if err := json.Unmarshal([]byte(combined), &c); err != nil {
+				return nil, fmt.Errorf("coordinates: %w", err)
+			}
+			return &c, nil
+		}
+
+		var ok bool
+		lat, lon, ok = strings.Cut(combined, ",")
+		if !ok {
+			return nil, fmt.Errorf("coordinates: invalid value %q", combined)
+		}
+	}
+
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	if err != nil {
+		return nil, fmt.Errorf("latitude: %w", err)
+	}
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(lon), 64)
+	if err != nil {
+		return nil, fmt.Errorf("longitude: %w", err)
+	}
+
+	return &Coordinate{Latitude: latitude, Longitude: longitude}, nil
+}
+
+// parseTags reads tags from a JSON array or a comma-separated list.
+func parseTags(v string) ([]string, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(v, "[") {
+		var tags []string
+		if err := json.Unmarshal([]byte(v), &tags); err != nil {
+			return nil, fmt.Errorf("tags: %w", err)
+		}
+		return tags, nil
+	}
+
+	var tags []string
+	for tag := range strings.SplitSeq(v, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// parseAttributes reads attributes from a JSON array of attributes or a JSON
+// object of name/value pairs, whose types are inferred from the values.
+func parseAttributes(v string) ([]Attribute, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(v, "[") {
+		var attrs []Attribute
+		if err := json.Unmarshal([]byte(v), &attrs); err != nil {
+			return nil, fmt.Errorf("attributes: %w", err)
+		}
+		return attrs, nil
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal([]byte(v), &values); err != nil {
+		return nil, fmt.Errorf("attributes: %w", err)
+	}
+
+	attrs := make([]Attribute, 0, len(values))
+	for name, value := range values {
+		attrs = append(attrs, Attribute{Name: name, Type: inferAttributeType(value), Value: value})
+	}
+	slices.SortFunc(attrs, func(a, b Attribute) int { return strings.Compare(a.Name, b.Name) })
+
+	return attrs, nil
+}
+
+func inferAttributeType(v any) AttributeType {
+	switch v.(type) {
+	case float64:
+		return AttrNumber
+	case bool:
+		return AttrBool
+	case []any:
+		return AttrList
+	case map[string]any:
+		return AttrObject
+	default:
+		return AttrText
+	}
+}
+
+// parseTimestamp reads a Unix timestamp in seconds or an RFC 3339 time.
+func parseTimestamp(v string) (UnixTime, error) {
+	if v == "" {
+		return UnixTime{}, nil
+	}
+
+	if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return UnixTime{Time: time.Unix(unix, 0)}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return UnixTime{}, err
+	}
+	return UnixTime{Time: t}, nil
+}
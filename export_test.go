@@ -0,0 +1,125 @@
+package leadsdb_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+// rangeIgnoringServer ignores Range headers and cuts the first response short.
+// Without an etag it serves a different export on every request.
+func rangeIgnoringServer(t *testing.T, etag string) *httptest.Server {
+	t.Helper()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body := strings.Repeat("export-"+strconv.Itoa(int(n))+"\n", 100)
+		if etag != "" {
+			// The same export, identified by its ETag.
+			body = strings.Repeat("export\n", 100)
+			w.Header().Set("ETag", etag)
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if n == 1 {
+			// Declare the full length but send only half, so the client sees an
+			// unexpected EOF.
+			_, _ = io.WriteString(w, body[:len(body)/2])
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestExportResumeWithoutETagFails(t *testing.T) {
+	srv := rangeIgnoringServer(t, "")
+	client := leadsdb.New("key", leadsdb.WithBaseURL(srv.URL))
+
+	r, err := client.Export(context.Background(), leadsdb.ExportCSV)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	defer r.Close()
+
+	_, err = io.ReadAll(r)
+	if err == nil || !strings.Contains(err.Error(), "export interrupted") {
+		t.Fatalf("got %v, want an export interrupted error", err)
+	}
+}
+
+func TestExportResumeSkipsAheadWhenETagMatches(t *testing.T) {
+	srv := rangeIgnoringServer(t, `"v1"`)
+	client := leadsdb.New("key", leadsdb.WithBaseURL(srv.URL))
+
+	r, err := client.Export(context.Background(), leadsdb.ExportCSV)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	if want := strings.Repeat("export\n", 100); string(data) != want {
+		t.Fatalf("got %d bytes, want the export read once", len(data))
+	}
+}
+
+func TestExportLeadsRoundTrip(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	want := srv.AddLead(leadsdb.Lead{
+		Name:        "Acme, Inc.",
+		Source:      "maps",
+		City:        "Berlin",
+		Rating:      leadsdb.Ptr(4.5),
+		Tags:        []string{"a", "b,c"},
+		Coordinates: &leadsdb.Coordinate{Latitude: 52.52, Longitude: 13.405},
+		Attributes:  []leadsdb.Attribute{leadsdb.NumberAttr("employees", 50), leadsdb.TextAttr("zip", "01234")},
+	})
+	client := srv.Client()
+
+	for _, format := range []leadsdb.ExportFormat{leadsdb.ExportCSV, leadsdb.ExportJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var got []*leadsdb.Lead
+			for lead, err := range client.ExportLeads(context.Background(), format) {
+				if err != nil {
+					t.Fatalf("ExportLeads: %v", err)
+				}
+				got = append(got, lead)
+			}
+
+			if len(got) != 1 {
+				t.Fatalf("got %d leads, want 1", len(got))
+			}
+			lead := got[0]
+			if lead.Name != want.Name || lead.City != want.City || *lead.Rating != *want.Rating {
+				t.Errorf("got %+v, want %+v", lead, want)
+			}
+			if !slices.Equal(lead.Tags, want.Tags) {
+				t.Errorf("tags = %q, want %q", lead.Tags, want.Tags)
+			}
+			if *lead.Coordinates != *want.Coordinates {
+				t.Errorf("coordinates = %v, want %v", *lead.Coordinates, *want.Coordinates)
+			}
+			if len(lead.Attributes) != 2 || lead.Attributes[1].Value != "01234" {
+				t.Errorf("attributes = %+v, want %+v", lead.Attributes, want.Attributes)
+			}
+		})
+	}
+}
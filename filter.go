@@ -15,6 +15,8 @@ type Field string
 
 func (f Field) sortFieldName() string { return string(f) }
 
+func (f Field) updateFieldName() string { return string(f) }
+
 // Known fields for type-safe sorting and filtering.
 const (
 	FieldName        Field = "name"
@@ -32,6 +34,29 @@ const (
 	FieldUpdatedAt   Field = "updated_at"
 )
 
+// UpdateField is a lead field that can be named in UpdateLeadInput.Clear and
+// Change: a Field, or a LeadField for fields that cannot be sorted on.
+type UpdateField interface {
+	updateFieldName() string
+}
+
+// LeadField represents a lead field that cannot be used for sorting.
+type LeadField string
+
+func (f LeadField) updateFieldName() string { return string(f) }
+
+// Further lead fields, for use with UpdateLeadInput.Clear and Change.
+const (
+	FieldDescription LeadField = "description"
+	FieldAddress     LeadField = "address"
+	FieldPostalCode  LeadField = "postal_code"
+	FieldCoordinates LeadField = "coordinates"
+	FieldTags        LeadField = "tags"
+	FieldSourceID    LeadField = "source_id"
+	FieldLogoURL     LeadField = "logo_url"
+	FieldAttributes  LeadField = "attributes"
+)
+
 // AttrSortField represents a custom attribute field for sorting.
 type AttrSortField string
 
@@ -84,6 +109,7 @@ func (b *OrBuilder) Phone() *TextField    { return &TextField{logic: logicOr, fi
 func (b *OrBuilder) Website() *TextField  { return &TextField{logic: logicOr, field: "website"} }
 func (b *OrBuilder) Category() *TextField { return &TextField{logic: logicOr, field: "category"} }
 func (b *OrBuilder) Source() *TextField   { return &TextField{logic: logicOr, field: "source"} }
+func (b *OrBuilder) SourceID() *TextField { return &TextField{logic: logicOr, field: "source_id"} }
 func (b *OrBuilder) Rating() *NumberField { return &NumberField{logic: logicOr, field: "rating"} }
 func (b *OrBuilder) ReviewCount() *NumberField {
 	return &NumberField{logic: logicOr, field: "review_count"}
@@ -102,6 +128,7 @@ func Phone() *TextField           { return &TextField{logic: logicAnd, field: "p
 func Website() *TextField         { return &TextField{logic: logicAnd, field: "website"} }
 func Category() *TextField        { return &TextField{logic: logicAnd, field: "category"} }
 func Source() *TextField          { return &TextField{logic: logicAnd, field: "source"} }
+func SourceID() *TextField        { return &TextField{logic: logicAnd, field: "source_id"} }
 func Rating() *NumberField        { return &NumberField{logic: logicAnd, field: "rating"} }
 func ReviewCount() *NumberField   { return &NumberField{logic: logicAnd, field: "review_count"} }
 func Tags() *ArrayField           { return &ArrayField{logic: logicAnd, field: "tags"} }
@@ -0,0 +1,231 @@
+package leadsdb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Condition is a filter or a group of filters that can be nested with AllOf, AnyOf and Not.
+type Condition interface {
+	ListOption
+	condition() node
+}
+
+type groupOp string
+
+const (
+	groupAll groupOp = "and"
+	groupAny groupOp = "or"
+	groupNot groupOp = "not"
+)
+
+// node is a condition tree. Groups set Op and Conditions; leaves set Field,
+// Operator and Value.
+type node struct {
+	Op         groupOp
+	Conditions []node
+	Field      string
+	Operator   string
+	Value      string
+}
+
+// empty reports whether n is a group without conditions, or the zero node.
+func (n node) empty() bool {
+	if n.Op == "" {
+		return n.Field == ""
+	}
+	return len(n.Conditions) == 0
+}
+
+func (f FilterOption) condition() node {
+	return node{Field: f.filter.field, Operator: f.filter.operator, Value: f.filter.value}
+}
+
+// FilterGroup is a boolean combination of conditions that can be passed to List.
+// A group is combined with other filters and groups using AND.
+//
+// The API has no nested conditions, so List and Export send groups as plain
+// AND/OR filters. That works for any nesting of AllOf and Not, but a request
+// can contain only one set of OR conditions: either Or filters or a single
+// AnyOf, whose conditions must be single filters. Not is applied by inverting
+// the operator, which is not possible for within_radius or for numeric
+// comparisons, since those never match an unset field. Groups that cannot be
+// sent make List and Export fail; Match evaluates any group.
+type FilterGroup struct {
+	root node
+}
+
+func (g FilterGroup) apply(cfg *listConfig) {
+	if !g.root.empty() {
+		cfg.groups = append(cfg.groups, g.root)
+	}
+}
+
+func (g FilterGroup) condition() node { return g.root }
+
+// String returns the group as a filter expression, as written by FormatFilters.
+func (g FilterGroup) String() string {
+	return FormatFilters(g)
+}
+
+// AllOf returns a group that matches when every condition matches.
+// The AND/OR logic of filters inside a group is ignored, and so are nil
+// conditions and groups without conditions.
+func AllOf(conds ...Condition) FilterGroup {
+	return FilterGroup{root: newGroup(groupAll, conds)}
+}
+
+// AnyOf returns a group that matches when at least one condition matches.
+// The AND/OR logic of filters inside a group is ignored, and so are nil
+// conditions and groups without conditions. A group without conditions
+// places no restriction, like an empty filter expression.
+func AnyOf(conds ...Condition) FilterGroup {
+	return FilterGroup{root: newGroup(groupAny, conds)}
+}
+
+// Not returns a group that matches when cond does not match. When cond is nil
+// or a group without conditions, so is the result.
+func Not(cond Condition) FilterGroup {
+	return FilterGroup{root: newGroup(groupNot, []Condition{cond})}
+}
+
+func newGroup(op groupOp, conds []Condition) node {
+	n := node{Op: op, Conditions: make([]node, 0, len(conds))}
+	for _, c := range conds {
+		if c == nil {
+			continue
+		}
+		if cn := c.condition(); !cn.empty() {
+			n.Conditions = append(n.Conditions, cn)
+		}
+	}
+	return n
+}
+
+// apiFilters returns filters and groups as the AND/OR filters the API accepts,
+// or an error when a group cannot be expressed with them.
+func apiFilters(filters []filter, groups []node) ([]filter, error) {
+	out := slices.Clone(filters)
+	hasOr := slices.ContainsFunc(filters, func(f filter) bool { return f.logic == logicOr })
+
+	for _, g := range groups {
+		ands, ors, err := g.flatten(false)
+		if err == nil && len(ors) > 0 && hasOr {
+			err = errors.New("only one set of OR conditions is supported per request")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("leadsdb: cannot send filter group %s: %w", FormatFilters(FilterGroup{root: g}), err)
+		}
+
+		out = append(out, ands...)
+		out = append(out, ors...)
+		hasOr = hasOr || len(ors) > 0
+	}
+
+	return out, nil
+}
+
+// flatten returns n, negated when neg is set, as filters that must all match
+// and filters of which at least one must match.
+func (n node) flatten(neg bool) (ands, ors []filter, err error) {
+	switch {
+	case n.Op == "":
+		f := filter{logic: logicAnd, operator: n.Operator, field: n.Field, value: n.Value}
+		if neg {
+			op, ok := negateOperator(f.field, f.operator)
+			if !ok {
+				return nil, nil, fmt.Errorf("%s %s has no opposite operator", f.field, f.operator)
+			}
+			f.operator = op
+		}
+		return []filter{f}, nil, nil
+
+	case n.Op == groupNot:
+		if len(n.Conditions) != 1 {
+			return nil, nil, fmt.Errorf("not requires exactly one condition, got %d", len(n.Conditions))
+		}
+		return n.Conditions[0].flatten(!neg)
+
+	case (n.Op == groupAll) != neg:
+		// AllOf, or a negated AnyOf.
+		for _, c := range n.Conditions {
+			a, o, err := c.flatten(neg)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(o) > 0 && len(ors) > 0 {
+				return nil, nil, errors.New("only one set of OR conditions is supported per request")
+			}
+			ands = append(ands, a...)
+			ors = append(ors, o...)
+		}
+		return ands, ors, nil
+
+	default:
+		// AnyOf, or a negated AllOf.
+		for _, c := range n.Conditions {
+			a, o, err := c.flatten(neg)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch {
+			case len(a) == 1 && len(o) == 0:
+				ors = append(ors, a...)
+			case len(a) == 0:
+				ors = append(ors, o...)
+			default:
+				return nil, nil, errors.New("alternatives must be single filters")
+			}
+		}
+		for i := range ors {
+			ors[i].logic = logicOr
+		}
+		if len(ors) == 1 {
+			ors[0].logic = logicAnd
+			return ors, nil, nil
+		}
+		return nil, ors, nil
+	}
+}
+
+// negateOperator returns the operator matching exactly the leads op does not.
+// Numeric comparisons never match an unset field, so they have no opposite.
+func negateOperator(field, op string) (string, bool) {
+	if field == "rating" || field == "review_count" {
+		return "", false
+	}
+	if strings.HasPrefix(field, "attr:") && op != "eq" && op != "neq" && op != "is_empty" && op != "is_not_empty" {
+		return "", false
+	}
+
+	switch op {
+	case "eq":
+		return "neq", true
+	case "neq":
+		return "eq", true
+	case "contains":
+		return "not_contains", true
+	case "not_contains":
+		return "contains", true
+	case "is_empty":
+		return "is_not_empty", true
+	case "is_not_empty":
+		return "is_empty", true
+	case "array_contains":
+		return "array_not_contains", true
+	case "array_not_contains":
+		return "array_contains", true
+	case "array_empty":
+		return "array_not_empty", true
+	case "array_not_empty":
+		return "array_empty", true
+	case "is_set":
+		return "is_not_set", true
+	case "is_not_set":
+		return "is_set", true
+	default:
+		return "", false
+	}
+}
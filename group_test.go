@@ -0,0 +1,154 @@
+package leadsdb_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestGroupsAreSentAsFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []leadsdb.ListOption
+		want []string
+	}{
+		{
+			"all of",
+			[]leadsdb.ListOption{leadsdb.AllOf(leadsdb.City().Eq("Berlin"), leadsdb.Rating().Gt(4))},
+			[]string{"and.eq.city.Berlin", "and.gt.rating.4"},
+		},
+		{
+			"nested",
+			[]leadsdb.ListOption{leadsdb.AllOf(
+				leadsdb.AnyOf(leadsdb.City().Eq("Berlin"), leadsdb.City().Eq("Paris")),
+				leadsdb.Rating().Gt(4),
+				leadsdb.Not(leadsdb.Tags().Contains("churned")),
+			)},
+			[]string{"and.gt.rating.4", "and.array_not_contains.tags.churned", "or.eq.city.Berlin", "or.eq.city.Paris"},
+		},
+		{
+			"not any of",
+			[]leadsdb.ListOption{leadsdb.Not(leadsdb.AnyOf(leadsdb.Email().IsEmpty(), leadsdb.Location().IsSet()))},
+			[]string{"and.is_not_empty.email", "and.is_not_set.location"},
+		},
+		{
+			"not all of",
+			[]leadsdb.ListOption{leadsdb.Source().Eq("maps"), leadsdb.Not(leadsdb.AllOf(leadsdb.City().Eq("Berlin"), leadsdb.Attr("tier").Eq("gold")))},
+			[]string{"and.eq.source.maps", "or.neq.city.Berlin", "or.neq.attr:tier.gold"},
+		},
+		{
+			"single alternative",
+			[]leadsdb.ListOption{leadsdb.Or().City().Eq("Paris"), leadsdb.AnyOf(leadsdb.Name().Contains("gmbh"))},
+			[]string{"or.eq.city.Paris", "and.contains.name.gmbh"},
+		},
+		{
+			"empty groups",
+			[]leadsdb.ListOption{leadsdb.AnyOf(), leadsdb.Not(nil), leadsdb.AllOf(nil, leadsdb.AnyOf())},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := leadsdbtest.NewServer()
+			defer srv.Close()
+
+			if _, err := srv.Client().List(context.Background(), tt.opts...); err != nil {
+				t.Fatalf("List: %v", err)
+			}
+
+			got := srv.Requests()[0].Query["filter"]
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("filters %q, want %q", got, tt.want)
+			}
+			if _, ok := srv.Requests()[0].Query["filter_group"]; ok {
+				t.Fatal("filter_group parameter sent")
+			}
+		})
+	}
+}
+
+func TestGroupsTheAPICannotExpress(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []leadsdb.ListOption
+		want string
+	}{
+		{
+			"two alternatives",
+			[]leadsdb.ListOption{
+				leadsdb.AnyOf(leadsdb.City().Eq("Berlin"), leadsdb.City().Eq("Paris")),
+				leadsdb.AnyOf(leadsdb.Tags().Contains("a"), leadsdb.Tags().Contains("b")),
+			},
+			"only one set of OR conditions",
+		},
+		{
+			"or filters and any of",
+			[]leadsdb.ListOption{
+				leadsdb.Or().City().Eq("Berlin"),
+				leadsdb.Or().City().Eq("Paris"),
+				leadsdb.AnyOf(leadsdb.Tags().Contains("a"), leadsdb.Tags().Contains("b")),
+			},
+			"only one set of OR conditions",
+		},
+		{
+			"conjunction inside any of",
+			[]leadsdb.ListOption{leadsdb.AnyOf(
+				leadsdb.AllOf(leadsdb.City().Eq("Berlin"), leadsdb.Rating().Gt(4)),
+				leadsdb.City().Eq("Paris"),
+			)},
+			"alternatives must be single filters",
+		},
+		{
+			"negated number",
+			[]leadsdb.ListOption{leadsdb.Not(leadsdb.Rating().Gt(4))},
+			"rating gt has no opposite operator",
+		},
+		{
+			"negated radius",
+			[]leadsdb.ListOption{leadsdb.Not(leadsdb.Location().WithinRadius(52.5, 13.4, 10))},
+			"location within_radius has no opposite operator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := leadsdbtest.NewServer()
+			defer srv.Close()
+			client := srv.Client()
+
+			_, err := client.List(context.Background(), tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("List: got %v, want an error containing %q", err, tt.want)
+			}
+			if _, err := client.Export(context.Background(), leadsdb.ExportJSON, tt.opts...); err == nil {
+				t.Fatal("Export succeeded, want an error")
+			}
+			if n := len(srv.Requests()); n != 0 {
+				t.Fatalf("server received %d requests, want none", n)
+			}
+		})
+	}
+}
+
+func TestEmptyGroupsRoundTrip(t *testing.T) {
+	lead := &leadsdb.Lead{Name: "Acme", Source: "test"}
+
+	for _, g := range []leadsdb.FilterGroup{leadsdb.AnyOf(), leadsdb.AllOf(), leadsdb.Not(nil), leadsdb.Not(leadsdb.AnyOf())} {
+		if s := leadsdb.FormatFilters(g); s != "" {
+			t.Errorf("FormatFilters = %q, want an empty expression", s)
+		}
+		if !leadsdb.Match(lead, g) {
+			t.Errorf("Match(%#v) = false, want an empty group to match like no filter", g)
+		}
+	}
+
+	g := leadsdb.AnyOf(leadsdb.City().Eq("Berlin"), nil, leadsdb.AnyOf())
+	if s := g.String(); s != `city = "Berlin"` {
+		t.Errorf("String = %q, want the nil and empty conditions dropped", s)
+	}
+}
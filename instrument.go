@@ -0,0 +1,189 @@
+package leadsdb
+
+import (
+	"context"
+	"expvar"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Instrumentation receives measurements of the client's activity, for example
+// to export them as metrics. Implementations must be safe for concurrent use.
+//
+// Embed NopInstrumentation to implement only some of the methods; methods
+// added to the interface in the future will be added to NopInstrumentation too.
+type Instrumentation interface {
+	// RequestDone is called after every HTTP request, including retries.
+	RequestDone(ctx context.Context, info RequestInfo)
+	// Retry is called before a failed request is retried, with the number of
+	// the attempt that failed and the delay before the next one.
+	Retry(ctx context.Context, method, path string, attempt int, delay time.Duration)
+	// BatchSent is called for every batch submitted by BulkCreateAll and the
+	// FromChan bulk methods, with the number of leads in the batch.
+	BatchSent(ctx context.Context, size int)
+	// PageFetched is called for every page fetched by Iterator and IteratorChan,
+	// with the number of leads on the page.
+	PageFetched(ctx context.Context, size int)
+}
+
+// RequestInfo describes a completed HTTP request.
+type RequestInfo struct {
+	Method string
+	// Path is the request path without the query string.
+	Path string
+	// Attempt is 1 for the first attempt and increases with every retry.
+	Attempt int
+	// StatusCode is 0 when no response was received.
+	StatusCode int
+	// Err is the error the request failed with, or nil.
+	Err error
+	// Duration is the time until the response body was read. For exports it
+	// includes the whole download, and RequestDone is called when the reader is closed.
+	Duration      time.Duration
+	BytesSent     int64
+	BytesReceived int64
+}
+
+// WithInstrumentation reports the client's activity to inst.
+// A nil inst disables instrumentation, which is the default.
+func WithInstrumentation(inst Instrumentation) Option {
+	return func(c *Client) {
+		if inst == nil {
+			inst = NopInstrumentation{}
+		}
+		c.inst = inst
+	}
+}
+
+// NopInstrumentation is an Instrumentation that does nothing.
+type NopInstrumentation struct{}
+
+// RequestDone implements Instrumentation.
+func (NopInstrumentation) RequestDone(context.Context, RequestInfo) {}
+
+// Retry implements Instrumentation.
+func (NopInstrumentation) Retry(context.Context, string, string, int, time.Duration) {}
+
+// BatchSent implements Instrumentation.
+func (NopInstrumentation) BatchSent(context.Context, int) {}
+
+// PageFetched implements Instrumentation.
+func (NopInstrumentation) PageFetched(context.Context, int) {}
+
+// latencyBuckets are the upper bounds of the latency histogram of ExpvarInstrumentation.
+var latencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// ExpvarInstrumentation publishes counters with the expvar package, so they
+// are served as JSON on /debug/vars.
+//
+// The published map contains:
+//
+//	requests, errors           requests sent and requests that failed
+//	status                     requests by status code, "0" for network errors
+//	retries                    retried requests
+//	bytes_sent, bytes_received request and response body bytes
+//	latency_seconds            cumulative latency histogram keyed by upper bound, and "+Inf"
+//	latency_seconds_sum        total latency
+//	batches, batch_leads       bulk batches and the leads in them
+//	pages, page_leads          pages fetched by iterators and the leads on them
+type ExpvarInstrumentation struct {
+	vars    *expvar.Map
+	status  *expvar.Map
+	latency *expvar.Map
+	sum     *expvar.Float
+}
+
+// NewExpvarInstrumentation publishes a map of counters under name.
+// Like expvar.NewMap, it panics if name is already in use.
+func NewExpvarInstrumentation(name string) *ExpvarInstrumentation {
+	e := &ExpvarInstrumentation{
+		vars:    expvar.NewMap(name),
+		status:  new(expvar.Map).Init(),
+		latency: new(expvar.Map).Init(),
+		sum:     new(expvar.Float),
+	}
+
+	e.vars.Set("status", e.status)
+	e.vars.Set("latency_seconds", e.latency)
+	e.vars.Set("latency_seconds_sum", e.sum)
+	for _, key := range []string{"requests", "errors", "retries", "bytes_sent", "bytes_received", "batches", "batch_leads", "pages", "page_leads"} {
+		e.vars.Add(key, 0)
+	}
+
+	return e
+}
+
+// RequestDone implements Instrumentation.
+func (e *ExpvarInstrumentation) RequestDone(_ context.Context, info RequestInfo) {
+	e.vars.Add("requests", 1)
+	if info.Err != nil {
+		e.vars.Add("errors", 1)
+	}
+	e.status.Add(strconv.Itoa(info.StatusCode), 1)
+	e.vars.Add("bytes_sent", info.BytesSent)
+	e.vars.Add("bytes_received", info.BytesReceived)
+
+	for _, bound := range latencyBuckets {
+		if info.Duration <= bound {
+			e.latency.Add(strconv.FormatFloat(bound.Seconds(), 'f', -1, 64), 1)
+		}
+	}
+	e.latency.Add("+Inf", 1)
+	e.sum.Add(info.Duration.Seconds())
+}
+
+// Retry implements Instrumentation.
+func (e *ExpvarInstrumentation) Retry(context.Context, string, string, int, time.Duration) {
+	e.vars.Add("retries", 1)
+}
+
+// BatchSent implements Instrumentation.
+func (e *ExpvarInstrumentation) BatchSent(_ context.Context, size int) {
+	e.vars.Add("batches", 1)
+	e.vars.Add("batch_leads", int64(size))
+}
+
+// PageFetched implements Instrumentation.
+func (e *ExpvarInstrumentation) PageFetched(_ context.Context, size int) {
+	e.vars.Add("pages", 1)
+	e.vars.Add("page_leads", int64(size))
+}
+
+// instrumentedBody counts the bytes read from a response body. When report is
+// set, it is called once the body is closed.
+type instrumentedBody struct {
+	io.ReadCloser
+	n      int64
+	err    error
+	once   sync.Once
+	report func(n int64, err error)
+}
+
+func (b *instrumentedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *instrumentedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.report != nil {
+		b.once.Do(func() { b.report(b.n, b.err) })
+	}
+	return err
+}
@@ -0,0 +1,164 @@
+package leadsdb_test
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+// recorder is an Instrumentation that keeps every RequestInfo.
+type recorder struct {
+	leadsdb.NopInstrumentation
+
+	mu    sync.Mutex
+	infos []leadsdb.RequestInfo
+}
+
+func (r *recorder) RequestDone(_ context.Context, info leadsdb.RequestInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.infos = append(r.infos, info)
+}
+
+func (r *recorder) requests() []leadsdb.RequestInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]leadsdb.RequestInfo(nil), r.infos...)
+}
+
+// expvarRuns keeps published names unique when tests run more than once.
+var expvarRuns atomic.Int64
+
+func TestExpvarInstrumentation(t *testing.T) {
+	name := fmt.Sprintf("leadsdb_test_expvar_%d", expvarRuns.Add(1))
+	inst := leadsdb.NewExpvarInstrumentation(name)
+	ctx := context.Background()
+
+	inst.RequestDone(ctx, leadsdb.RequestInfo{StatusCode: 200, Duration: 5 * time.Millisecond, BytesSent: 10, BytesReceived: 100})
+	inst.RequestDone(ctx, leadsdb.RequestInfo{StatusCode: 503, Duration: 200 * time.Millisecond, BytesReceived: 50, Err: io.ErrUnexpectedEOF})
+	inst.RequestDone(ctx, leadsdb.RequestInfo{Duration: 20 * time.Second, Err: io.EOF})
+	inst.Retry(ctx, http.MethodGet, "/leads", 1, time.Second)
+	inst.BatchSent(ctx, 100)
+	inst.BatchSent(ctx, 7)
+	inst.PageFetched(ctx, 50)
+
+	vars := expvar.Get(name).(*expvar.Map)
+	get := func(m *expvar.Map, key string) string {
+		t.Helper()
+		v := m.Get(key)
+		if v == nil {
+			t.Fatalf("%s is not published", key)
+		}
+		return v.String()
+	}
+
+	counters := map[string]string{
+		"requests":       "3",
+		"errors":         "2",
+		"retries":        "1",
+		"bytes_sent":     "10",
+		"bytes_received": "150",
+		"batches":        "2",
+		"batch_leads":    "107",
+		"pages":          "1",
+		"page_leads":     "50",
+	}
+	for key, want := range counters {
+		if got := get(vars, key); got != want {
+			t.Errorf("%s = %s, want %s", key, got, want)
+		}
+	}
+
+	status := vars.Get("status").(*expvar.Map)
+	for key, want := range map[string]string{"200": "1", "503": "1", "0": "1"} {
+		if got := get(status, key); got != want {
+			t.Errorf("status[%s] = %s, want %s", key, got, want)
+		}
+	}
+
+	// Buckets are cumulative: each request counts in every bucket at or above its latency.
+	latency := vars.Get("latency_seconds").(*expvar.Map)
+	buckets := map[string]string{
+		"0.01": "1", "0.05": "1", "0.1": "1", "0.25": "2", "0.5": "2",
+		"1": "2", "2.5": "2", "5": "2", "10": "2", "+Inf": "3",
+	}
+	for key, want := range buckets {
+		if got := get(latency, key); got != want {
+			t.Errorf("latency_seconds[%s] = %s, want %s", key, got, want)
+		}
+	}
+	if got := get(vars, "latency_seconds_sum"); got != "20.205" {
+		t.Errorf("latency_seconds_sum = %s, want 20.205", got)
+	}
+}
+
+func TestInstrumentationCountsBytes(t *testing.T) {
+	const response = `{"id":"lead_1","name":"Acme","source":"test"}`
+
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+		io.WriteString(w, response)
+	}))
+	defer srv.Close()
+
+	rec := &recorder{}
+	client := leadsdb.New("key", leadsdb.WithBaseURL(srv.URL), leadsdb.WithInstrumentation(rec))
+
+	if _, err := client.Create(context.Background(), &leadsdb.Lead{Name: "Acme", Source: "test"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	infos := rec.requests()
+	if len(infos) != 1 {
+		t.Fatalf("got %d reports, want 1", len(infos))
+	}
+	if info := infos[0]; info.BytesSent != int64(received) || info.BytesReceived != int64(len(response)) {
+		t.Fatalf("got %d bytes sent and %d received, want %d and %d", info.BytesSent, info.BytesReceived, received, len(response))
+	}
+}
+
+func TestInstrumentationCountsExportBytes(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	for range 20 {
+		srv.AddLead(leadsdb.Lead{Name: strings.Repeat("Acme ", 20), Source: "test"})
+	}
+	rec := &recorder{}
+	client := srv.Client(leadsdb.WithInstrumentation(rec))
+
+	export, err := client.Export(context.Background(), leadsdb.ExportCSV)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	data, err := io.ReadAll(export)
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	if n := len(rec.requests()); n != 0 {
+		t.Fatalf("got %d reports before the export was closed, want 0", n)
+	}
+	export.Close()
+	export.Close()
+
+	infos := rec.requests()
+	if len(infos) != 1 {
+		t.Fatalf("got %d reports, want 1", len(infos))
+	}
+	if info := infos[0]; info.BytesReceived != int64(len(data)) || info.Err != nil {
+		t.Fatalf("got %d bytes received and error %v, want %d and nil", info.BytesReceived, info.Err, len(data))
+	}
+}
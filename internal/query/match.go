@@ -0,0 +1,347 @@
+package query
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// earthRadiusKm is the mean Earth radius used for radius filters.
+const earthRadiusKm = 6371.0
+
+// Record holds the fields of a lead that filters and sorting read. Package
+// leadsdb and leadsdbtest each build it from a *leadsdb.Lead.
+type Record struct {
+	Name        string
+	Source      string
+	Description string
+	Address     string
+	City        string
+	State       string
+	Country     string
+	PostalCode  string
+	Phone       string
+	Email       string
+	Website     string
+	Category    string
+	SourceID    string
+	LogoURL     string
+	Rating      *float64
+	ReviewCount *int
+	Tags        []string
+	Coordinates *Coordinate
+	Attributes  []Attribute
+}
+
+// Coordinate is the location of a lead.
+type Coordinate struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Attribute is a custom attribute of a lead.
+type Attribute struct {
+	Name  string
+	Value any
+}
+
+// Text returns the value of a text field by its API name, or "" for unknown fields.
+func (r *Record) Text(field string) string {
+	switch field {
+	case "name":
+		return r.Name
+	case "source":
+		return r.Source
+	case "description":
+		return r.Description
+	case "address":
+		return r.Address
+	case "city":
+		return r.City
+	case "state":
+		return r.State
+	case "country":
+		return r.Country
+	case "postal_code":
+		return r.PostalCode
+	case "phone":
+		return r.Phone
+	case "email":
+		return r.Email
+	case "website":
+		return r.Website
+	case "category":
+		return r.Category
+	case "source_id":
+		return r.SourceID
+	case "logo_url":
+		return r.LogoURL
+	default:
+		return ""
+	}
+}
+
+// Number returns the value of rating or review_count, if set.
+func (r *Record) Number(field string) (float64, bool) {
+	switch {
+	case field == "rating" && r.Rating != nil:
+		return *r.Rating, true
+	case field == "review_count" && r.ReviewCount != nil:
+		return float64(*r.ReviewCount), true
+	default:
+		return 0, false
+	}
+}
+
+// Attr returns the value of the named attribute, if present.
+func (r *Record) Attr(name string) (any, bool) {
+	for _, a := range r.Attributes {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Match reports whether r satisfies filters and groups: every AND filter must
+// match and, when OR filters are present, at least one of them must match.
+// Every group must match as well.
+func Match(r *Record, filters []Filter, groups []Group) bool {
+	hasOr, anyOr := false, false
+	for _, f := range filters {
+		ok := f.match(r)
+		if f.Logic == Or {
+			hasOr = true
+			anyOr = anyOr || ok
+			continue
+		}
+		if !ok {
+			return false
+		}
+	}
+	if hasOr && !anyOr {
+		return false
+	}
+
+	for _, g := range groups {
+		if !g.match(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (g Group) match(r *Record) bool {
+	switch g.Op {
+	case And:
+		for _, c := range g.Conditions {
+			if !c.match(r) {
+				return false
+			}
+		}
+		return true
+	case Or:
+		for _, c := range g.Conditions {
+			if c.match(r) {
+				return true
+			}
+		}
+		return false
+	case Not:
+		return len(g.Conditions) == 1 && !g.Conditions[0].match(r)
+	default:
+		return Filter{Operator: g.Operator, Field: g.Field, Value: g.Value}.match(r)
+	}
+}
+
+func (f Filter) match(r *Record) bool {
+	switch {
+	case f.Field == "tags":
+		return matchArray(r.Tags, f.Operator, f.Value)
+	case f.Field == "location":
+		return matchLocation(r, f.Operator, f.Value)
+	case f.Field == "rating" || f.Field == "review_count":
+		n, ok := r.Number(f.Field)
+		if !ok {
+			return false
+		}
+		return matchNumber(n, f.Operator, f.Value)
+	case strings.HasPrefix(f.Field, "attr:"):
+		return matchAttr(r, strings.TrimPrefix(f.Field, "attr:"), f.Operator, f.Value)
+	default:
+		return matchText(r.Text(f.Field), f.Operator, f.Value)
+	}
+}
+
+// matchAttr compares numerically when both the attribute and the filter value
+// are numbers, and as text otherwise. Missing attributes behave as empty text.
+func matchAttr(r *Record, name, op, value string) bool {
+	v, ok := r.Attr(name)
+	if !ok {
+		return matchText("", op, value)
+	}
+
+	if n, isNum := Number(v); isNum {
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return matchNumber(n, op, value)
+		}
+	}
+
+	return matchText(Text(v), op, value)
+}
+
+func matchText(v, op, value string) bool {
+	switch op {
+	case "eq":
+		return strings.EqualFold(v, value)
+	case "neq":
+		return !strings.EqualFold(v, value)
+	case "contains":
+		return strings.Contains(strings.ToLower(v), strings.ToLower(value))
+	case "not_contains":
+		return !strings.Contains(strings.ToLower(v), strings.ToLower(value))
+	case "is_empty":
+		return v == ""
+	case "is_not_empty":
+		return v != ""
+	default:
+		return false
+	}
+}
+
+func matchNumber(v float64, op, value string) bool {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+
+	switch op {
+	case "eq":
+		return v == n
+	case "neq":
+		return v != n
+	case "gt":
+		return v > n
+	case "gte":
+		return v >= n
+	case "lt":
+		return v < n
+	case "lte":
+		return v <= n
+	default:
+		return false
+	}
+}
+
+func matchArray(v []string, op, value string) bool {
+	contains := slices.ContainsFunc(v, func(s string) bool { return strings.EqualFold(s, value) })
+
+	switch op {
+	case "array_contains":
+		return contains
+	case "array_not_contains":
+		return !contains
+	case "array_empty":
+		return len(v) == 0
+	case "array_not_empty":
+		return len(v) > 0
+	default:
+		return false
+	}
+}
+
+func matchLocation(r *Record, op, value string) bool {
+	c := r.Coordinates
+
+	switch op {
+	case "is_set":
+		return c != nil
+	case "is_not_set":
+		return c == nil
+	case "within_radius":
+		if c == nil {
+			return false
+		}
+		lat, lon, km, ok := parseRadius(value)
+		if !ok {
+			return false
+		}
+		return haversine(c.Latitude, c.Longitude, lat, lon) <= km
+	default:
+		return false
+	}
+}
+
+// parseRadius decodes a within_radius value of the form lat,lon,km.
+func parseRadius(value string) (lat, lon, km float64, ok bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return 0, 0, 0, false
+	}
+
+	var nums [3]float64
+	for i, p := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		nums[i] = n
+	}
+
+	return nums[0], nums[1], nums[2], true
+}
+
+// haversine returns the great-circle distance in kilometers between two points.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// Number returns an attribute value as a number, if it is one or is a
+// string holding one.
+func Number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// Text returns an attribute value as text.
+func Text(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		data, _ := json.Marshal(t)
+		return string(data)
+	}
+}
@@ -0,0 +1,72 @@
+// Package query decodes the filter query parameters of the LeadsDB API and
+// evaluates them against leads. It is shared by package leadsdb, which encodes
+// the parameters, and the fake server in leadsdbtest, which decodes them.
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter logic and group operators.
+const (
+	And = "and"
+	Or  = "or"
+	Not = "not"
+)
+
+// Filter is a single filter, encoded as logic.operator.field[.value].
+type Filter struct {
+	Logic    string
+	Operator string
+	Field    string
+	Value    string
+}
+
+// Group is a condition tree. Groups set Op and Conditions; leaves set Field,
+// Operator and Value.
+type Group struct {
+	Op         string
+	Conditions []Group
+	Field      string
+	Operator   string
+	Value      string
+}
+
+// ParseFilter decodes a filter from its query parameter encoding.
+func ParseFilter(s string) (Filter, error) {
+	parts := strings.SplitN(s, ".", 4)
+	if len(parts) < 3 {
+		return Filter{}, fmt.Errorf("leadsdb: invalid filter %q", s)
+	}
+
+	f := Filter{Logic: parts[0], Operator: parts[1], Field: parts[2]}
+	if len(parts) == 4 {
+		f.Value = parts[3]
+	}
+
+	if f.Logic != And && f.Logic != Or {
+		return Filter{}, fmt.Errorf("leadsdb: invalid filter %q: unknown logic %q", s, f.Logic)
+	}
+	if f.Field == "" {
+		return Filter{}, fmt.Errorf("leadsdb: invalid filter %q: field is required", s)
+	}
+	if !KnownOperator(f.Operator) {
+		return Filter{}, fmt.Errorf("leadsdb: invalid filter %q: unknown operator %q", s, f.Operator)
+	}
+
+	return f, nil
+}
+
+// KnownOperator reports whether op is a filter operator supported by the API.
+func KnownOperator(op string) bool {
+	switch op {
+	case "eq", "neq", "contains", "not_contains", "is_empty", "is_not_empty",
+		"gt", "gte", "lt", "lte",
+		"array_contains", "array_not_contains", "array_empty", "array_not_empty",
+		"within_radius", "is_set", "is_not_set":
+		return true
+	default:
+		return false
+	}
+}
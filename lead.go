@@ -1,6 +1,14 @@
 // Package leadsdb defines the data structures for managing business leads.
 package leadsdb
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
 // Lead represents a business lead in the system.
 type Lead struct {
 	// Core identifiers
@@ -68,8 +76,14 @@ type Lead struct {
 	CreatedAt UnixTime `json:"created_at"`
 	// UpdatedAt is the timestamp when the lead was last updated.
 	UpdatedAt UnixTime `json:"updated_at"`
+
+	// ETag identifies the version of the lead returned by Get, Create and Update.
+	// Pass it to IfMatch to update the lead only if it has not changed since.
+	ETag string `json:"-"`
 }
 
+func (l *Lead) setETag(etag string) { l.ETag = etag }
+
 // Coordinate represents geographical coordinates.
 type Coordinate struct {
 	// Latitude is the latitude value.
@@ -80,6 +94,11 @@ type Coordinate struct {
 
 // UpdateLeadInput contains the fields for updating an existing lead.
 // All fields are optional; only non-nil fields will be updated.
+// Fields listed in Clear are removed from the lead.
+//
+// Tags and Attributes replace the whole list. To change only some entries,
+// which is safe when several writers update the same lead, use AddTags,
+// RemoveTags, SetAttributes and DeleteAttributes instead.
 type UpdateLeadInput struct {
 	Name        *string `json:"name,omitempty"`
 	Source      *string `json:"source,omitempty"`
@@ -112,6 +131,103 @@ type UpdateLeadInput struct {
 
 	// Dynamic attributes (replaces all existing attributes)
 	Attributes []Attribute `json:"attributes,omitempty"`
+
+	// AddTags adds tags the lead does not have yet.
+	AddTags []string `json:"add_tags,omitempty"`
+	// RemoveTags removes tags from the lead. Tags it does not have are ignored.
+	// A tag cannot be in both AddTags and RemoveTags.
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	// SetAttributes adds attributes, or replaces the existing ones with the same name.
+	SetAttributes []Attribute `json:"set_attributes,omitempty"`
+	// DeleteAttributes removes the attributes with these names. An attribute
+	// cannot be both set and deleted.
+	DeleteAttributes []string `json:"delete_attributes,omitempty"`
+
+	// Clear lists fields to remove from the lead, which are sent as JSON null.
+	// Name and Source cannot be cleared, and a cleared field must not also be set.
+	Clear []UpdateField `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields in Clear as null.
+// It does not validate the input; Update and BulkUpdate do that before sending.
+func (in UpdateLeadInput) MarshalJSON() ([]byte, error) {
+	type plain UpdateLeadInput
+
+	data, err := json.Marshal(plain(in))
+	if err != nil || len(in.Clear) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, f := range in.Clear {
+		if f != nil {
+			fields[f.updateFieldName()] = json.RawMessage("null")
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// clearableFields are the fields that can be listed in UpdateLeadInput.Clear.
+var clearableFields = []UpdateField{
+	FieldDescription, FieldAddress, FieldCity, FieldState, FieldCountry, FieldPostalCode, FieldCoordinates,
+	FieldPhone, FieldEmail, FieldWebsite, FieldRating, FieldReviewCount,
+	FieldCategory, FieldTags, FieldSourceID, FieldLogoURL, FieldAttributes,
+}
+
+// validate checks that the fields in Clear can be cleared and are not also set,
+// that Tags and Attributes are not combined with their patch operations, and
+// that no tag or attribute is both added and removed.
+func (in *UpdateLeadInput) validate() error {
+	tagOps := len(in.AddTags) > 0 || len(in.RemoveTags) > 0
+	if tagOps && (in.Tags != nil || slices.Contains(in.Clear, UpdateField(FieldTags))) {
+		return errors.New("leadsdb: tags cannot be both replaced and patched")
+	}
+	attrOps := len(in.SetAttributes) > 0 || len(in.DeleteAttributes) > 0
+	if attrOps && (in.Attributes != nil || slices.Contains(in.Clear, UpdateField(FieldAttributes))) {
+		return errors.New("leadsdb: attributes cannot be both replaced and patched")
+	}
+	for _, tag := range in.AddTags {
+		if slices.Contains(in.RemoveTags, tag) {
+			return fmt.Errorf("leadsdb: tag %q is both added and removed", tag)
+		}
+	}
+	for _, attr := range in.SetAttributes {
+		if slices.Contains(in.DeleteAttributes, attr.Name) {
+			return fmt.Errorf("leadsdb: attribute %q is both set and deleted", attr.Name)
+		}
+	}
+
+	if len(in.Clear) == 0 {
+		return nil
+	}
+
+	type plain UpdateLeadInput
+	data, err := json.Marshal((*plain)(in))
+	if err != nil {
+		return err
+	}
+	var set map[string]json.RawMessage
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+
+	for _, f := range in.Clear {
+		if f == nil {
+			return errors.New("leadsdb: nil field in Clear")
+		}
+		if !slices.Contains(clearableFields, f) {
+			return fmt.Errorf("leadsdb: field %q cannot be cleared", f.updateFieldName())
+		}
+		if _, ok := set[f.updateFieldName()]; ok {
+			return fmt.Errorf("leadsdb: field %q is both set and cleared", f.updateFieldName())
+		}
+	}
+
+	return nil
 }
 
 // BulkCreateResult contains the result of a bulk create operation.
@@ -128,10 +244,32 @@ type BulkLeadResult struct {
 	Index     int      `json:"index"`
 	ID        string   `json:"id"`
 	CreatedAt UnixTime `json:"created_at"`
+
+	// Lead and Batch are filled in by BulkCreateAll and BulkCreateFromChan.
+	// Lead is the submitted lead and Batch the zero-based number of the request it was sent in.
+	Lead  *Lead `json:"-"`
+	Batch int   `json:"-"`
 }
 
 // BulkLeadError contains the error for a failed lead creation.
 type BulkLeadError struct {
 	Index   int    `json:"index"`
 	Message string `json:"message"`
+
+	// Lead and Batch are filled in by BulkCreateAll and BulkCreateFromChan.
+	// Lead is the submitted lead and Batch the zero-based number of the request it was sent in.
+	Lead  *Lead `json:"-"`
+	Batch int   `json:"-"`
+	// Err is the cause when the whole batch failed, rather than the API rejecting this lead.
+	Err error `json:"-"`
+}
+
+// Error implements the error interface.
+func (e *BulkLeadError) Error() string {
+	return fmt.Sprintf("leadsdb: lead at index %d: %s", e.Index, strings.TrimPrefix(e.Message, "leadsdb: "))
+}
+
+// Unwrap returns the batch error, if any.
+func (e *BulkLeadError) Unwrap() error {
+	return e.Err
 }
@@ -0,0 +1,96 @@
+package leadsdb_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestUpdateClear(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	stored := srv.AddLead(leadsdb.Lead{
+		Name:        "Acme",
+		Source:      "test",
+		City:        "Berlin",
+		Rating:      leadsdb.Ptr(4.5),
+		Tags:        []string{"saas"},
+		Coordinates: &leadsdb.Coordinate{Latitude: 52.52, Longitude: 13.405},
+	})
+	client := srv.Client()
+	ctx := context.Background()
+
+	lead, err := client.Update(ctx, stored.ID, &leadsdb.UpdateLeadInput{
+		Clear: []leadsdb.UpdateField{leadsdb.FieldCity, leadsdb.FieldRating, leadsdb.FieldTags, leadsdb.FieldCoordinates},
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if lead.City != "" || lead.Rating != nil || lead.Tags != nil || lead.Coordinates != nil {
+		t.Errorf("fields not cleared: %+v", lead)
+	}
+
+	tests := []struct {
+		name  string
+		input leadsdb.UpdateLeadInput
+	}{
+		{"required field", leadsdb.UpdateLeadInput{Clear: []leadsdb.UpdateField{leadsdb.FieldName}}},
+		{"set and cleared", leadsdb.UpdateLeadInput{City: leadsdb.Ptr("Paris"), Clear: []leadsdb.UpdateField{leadsdb.FieldCity}}},
+		{"nil field", leadsdb.UpdateLeadInput{Clear: []leadsdb.UpdateField{nil}}},
+		{"tags replaced and patched", leadsdb.UpdateLeadInput{AddTags: []string{"b2b"}, Clear: []leadsdb.UpdateField{leadsdb.FieldTags}}},
+		{"tag added and removed", leadsdb.UpdateLeadInput{AddTags: []string{"b2b", "saas"}, RemoveTags: []string{"saas"}}},
+		{"attribute set and deleted", leadsdb.UpdateLeadInput{
+			SetAttributes:    []leadsdb.Attribute{{Name: "tier", Value: "gold"}},
+			DeleteAttributes: []string{"tier"},
+		}},
+	}
+	sent := len(srv.Requests())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.Update(ctx, stored.ID, &tt.input); err == nil {
+				t.Fatal("Update succeeded, want an error")
+			}
+			if got := len(srv.Requests()); got != sent {
+				t.Fatalf("invalid input was sent: %d requests, want %d", got, sent)
+			}
+		})
+	}
+}
+
+func TestUpdateLeadInputMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input leadsdb.UpdateLeadInput
+		want  string
+	}{
+		{"clear", leadsdb.UpdateLeadInput{Rating: leadsdb.Ptr(4.0), Clear: []leadsdb.UpdateField{leadsdb.FieldCity}},
+			`{"city":null,"rating":4}`},
+		{"patch operations", leadsdb.UpdateLeadInput{
+			AddTags:          []string{"vip"},
+			RemoveTags:       []string{"new"},
+			SetAttributes:    []leadsdb.Attribute{leadsdb.TextAttr("tier", "gold"), leadsdb.NumberAttr("employees", 50)},
+			DeleteAttributes: []string{"legacy_id"},
+		}, `{"add_tags":["vip"],"remove_tags":["new"],` +
+			`"set_attributes":[{"name":"tier","type":"text","value":"gold"},{"name":"employees","type":"number","value":50}],` +
+			`"delete_attributes":["legacy_id"]}`},
+		// Encoding does not validate: the field is both set and cleared.
+		{"invalid", leadsdb.UpdateLeadInput{City: leadsdb.Ptr("Paris"), Clear: []leadsdb.UpdateField{leadsdb.FieldCity}},
+			`{"city":null}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Fatalf("got %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,657 @@
+package leadsdbtest
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/internal/query"
+)
+
+type listResponse struct {
+	Leads      []leadsdb.Lead `json:"leads"`
+	Count      int            `json:"count"`
+	HasMore    bool           `json:"has_more"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := DefaultPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid_limit", "limit must be a positive integer")
+			return
+		}
+		limit = min(n, MaxPageSize)
+	}
+
+	offset := 0
+	if v := q.Get("cursor"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_cursor", "invalid cursor")
+			return
+		}
+		offset = n
+	}
+
+	matched, ok := s.queryOrError(w, q)
+	if !ok {
+		return
+	}
+
+	resp := listResponse{Leads: []leadsdb.Lead{}}
+	if offset < len(matched) {
+		end := min(offset+limit, len(matched))
+		resp.Leads = matched[offset:end]
+		if end < len(matched) {
+			resp.HasMore = true
+			resp.NextCursor = strconv.Itoa(end)
+		}
+	}
+	resp.Count = len(resp.Leads)
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// queryOrError runs the filter and sort parameters of q, writing an error
+// response when they are invalid.
+func (s *Server) queryOrError(w http.ResponseWriter, q url.Values) ([]leadsdb.Lead, bool) {
+	sortOrder := strings.ToUpper(q.Get("sort_order"))
+	if sortOrder != "" && sortOrder != string(leadsdb.Asc) && sortOrder != string(leadsdb.Desc) {
+		writeError(w, http.StatusBadRequest, "invalid_sort_order", "sort_order must be ASC or DESC")
+		return nil, false
+	}
+
+	matched, err := s.query(q["filter"], q.Get("sort_by"), leadsdb.SortOrder(sortOrder))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_filter", err.Error())
+		return nil, false
+	}
+
+	return matched, true
+}
+
+// query returns copies of the stored leads matching all filters, sorted by sortBy.
+func (s *Server) query(filters []string, sortBy string, order leadsdb.SortOrder) ([]leadsdb.Lead, error) {
+	parsedFilters := make([]query.Filter, 0, len(filters))
+	for _, raw := range filters {
+		f, err := query.ParseFilter(raw)
+		if err != nil {
+			return nil, err
+		}
+		parsedFilters = append(parsedFilters, f)
+	}
+
+	s.mu.Lock()
+	var matched []leadsdb.Lead
+	for _, id := range s.order {
+		lead := s.leads[id]
+		if query.Match(record(lead), parsedFilters, nil) {
+			matched = append(matched, *cloneLead(lead))
+		}
+	}
+	s.mu.Unlock()
+
+	if sortBy != "" {
+		slices.SortStableFunc(matched, func(a, b leadsdb.Lead) int {
+			c := compareField(&a, &b, sortBy)
+			if order == leadsdb.Desc {
+				return -c
+			}
+			return c
+		})
+	}
+
+	return matched, nil
+}
+
+func compareField(a, b *leadsdb.Lead, field string) int {
+	switch field {
+	case "rating":
+		return compareOptional(a.Rating, b.Rating)
+	case "review_count":
+		return compareOptional(a.ReviewCount, b.ReviewCount)
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt.Time)
+	case "updated_at":
+		return a.UpdatedAt.Compare(b.UpdatedAt.Time)
+	}
+
+	ra, rb := record(a), record(b)
+	if name, ok := strings.CutPrefix(field, "attr:"); ok {
+		av, aok := ra.Attr(name)
+		bv, bok := rb.Attr(name)
+		if !aok || !bok {
+			return compareBool(aok, bok)
+		}
+		an, aNum := query.Number(av)
+		bn, bNum := query.Number(bv)
+		if aNum && bNum {
+			return cmp.Compare(an, bn)
+		}
+		return strings.Compare(strings.ToLower(query.Text(av)), strings.ToLower(query.Text(bv)))
+	}
+
+	return strings.Compare(strings.ToLower(ra.Text(field)), strings.ToLower(rb.Text(field)))
+}
+
+// compareOptional orders nil values after set values.
+func compareOptional[T cmp.Ordered](a, b *T) int {
+	if a == nil || b == nil {
+		return compareBool(a != nil, b != nil)
+	}
+	return cmp.Compare(*a, *b)
+}
+
+// compareBool orders true before false.
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	lead, ok := s.leads[r.PathValue("id")]
+	var out *leadsdb.Lead
+	if ok {
+		out = cloneLead(lead)
+		out.Notes = s.leadNotes(lead.ID)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "lead not found")
+		return
+	}
+
+	w.Header().Set("ETag", leadETag(out))
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var lead leadsdb.Lead
+	if err := json.NewDecoder(r.Body).Decode(&lead); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if fe := validateLead(&lead); fe != nil {
+		writeValidationError(w, *fe)
+		return
+	}
+
+	lead.ID = ""
+	lead.CreatedAt = leadsdb.UnixTime{}
+	lead.UpdatedAt = leadsdb.UnixTime{}
+
+	s.mu.Lock()
+	created := cloneLead(s.insertLead(&lead))
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", leadETag(created))
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) handleBulkCreate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Leads []*leadsdb.Lead `json:"leads"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if len(body.Leads) == 0 {
+		writeError(w, http.StatusBadRequest, "validation_error", "leads is required")
+		return
+	}
+	if len(body.Leads) > maxBatchSize {
+		writeError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("maximum %d leads allowed", maxBatchSize))
+		return
+	}
+
+	result := leadsdb.BulkCreateResult{
+		Total:   len(body.Leads),
+		Created: []leadsdb.BulkLeadResult{},
+		Errors:  []leadsdb.BulkLeadError{},
+	}
+
+	s.mu.Lock()
+	for i, lead := range body.Leads {
+		if lead == nil {
+			result.Errors = append(result.Errors, leadsdb.BulkLeadError{Index: i, Message: "lead is required"})
+			continue
+		}
+		if fe := validateLead(lead); fe != nil {
+			result.Errors = append(result.Errors, leadsdb.BulkLeadError{Index: i, Message: fe.Field + " " + fe.Message})
+			continue
+		}
+
+		lead.ID = ""
+		lead.CreatedAt = leadsdb.UnixTime{}
+		lead.UpdatedAt = leadsdb.UnixTime{}
+
+		created := s.insertLead(lead)
+		result.Created = append(result.Created, leadsdb.BulkLeadResult{
+			Index:     i,
+			ID:        created.ID,
+			CreatedAt: created.CreatedAt,
+		})
+	}
+	s.mu.Unlock()
+
+	result.Success = len(result.Created)
+	result.Failed = len(result.Errors)
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleBulkUpdate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Updates []struct {
+			ID    string                     `json:"id"`
+			Input map[string]json.RawMessage `json:"input"`
+		} `json:"updates"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if len(body.Updates) == 0 {
+		writeError(w, http.StatusBadRequest, "validation_error", "updates is required")
+		return
+	}
+	if len(body.Updates) > maxBatchSize {
+		writeError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("maximum %d updates allowed", maxBatchSize))
+		return
+	}
+
+	result := leadsdb.BulkUpdateResult{
+		Total:   len(body.Updates),
+		Updated: []leadsdb.BulkUpdatedLead{},
+		Errors:  []leadsdb.BulkUpdateError{},
+	}
+
+	s.mu.Lock()
+	now := leadsdb.UnixTime{Time: time.Now().Truncate(time.Second)}
+	for i, u := range body.Updates {
+		lead, ok := s.leads[u.ID]
+		if !ok {
+			result.Errors = append(result.Errors, leadsdb.BulkUpdateError{Index: i, ID: u.ID, Message: "lead not found"})
+			continue
+		}
+
+		updated, err := applyUpdate(lead, u.Input)
+		if err != nil {
+			result.Errors = append(result.Errors, leadsdb.BulkUpdateError{Index: i, ID: u.ID, Message: err.Error()})
+			continue
+		}
+		if fe := validateLead(updated); fe != nil {
+			result.Errors = append(result.Errors, leadsdb.BulkUpdateError{Index: i, ID: u.ID, Message: fe.Field + " " + fe.Message})
+			continue
+		}
+
+		updated.UpdatedAt = now
+		s.leads[updated.ID] = updated
+		result.Updated = append(result.Updated, leadsdb.BulkUpdatedLead{
+			Index:     i,
+			ID:        updated.ID,
+			UpdatedAt: updated.UpdatedAt,
+		})
+	}
+	s.mu.Unlock()
+
+	result.Success = len(result.Updated)
+	result.Failed = len(result.Errors)
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleBulkDelete(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if len(body.IDs) == 0 {
+		writeError(w, http.StatusBadRequest, "validation_error", "ids is required")
+		return
+	}
+	if len(body.IDs) > maxBatchSize {
+		writeError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("maximum %d ids allowed", maxBatchSize))
+		return
+	}
+
+	result := leadsdb.BulkDeleteResult{
+		Total:   len(body.IDs),
+		Deleted: []leadsdb.BulkDeletedLead{},
+		Errors:  []leadsdb.BulkDeleteError{},
+	}
+
+	s.mu.Lock()
+	for i, id := range body.IDs {
+		if !s.deleteLead(id) {
+			result.Errors = append(result.Errors, leadsdb.BulkDeleteError{Index: i, ID: id, Message: "lead not found"})
+			continue
+		}
+		result.Deleted = append(result.Deleted, leadsdb.BulkDeletedLead{Index: i, ID: id})
+	}
+	s.mu.Unlock()
+
+	result.Success = len(result.Deleted)
+	result.Failed = len(result.Errors)
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// readOnlyFields are ignored when applying an update.
+var readOnlyFields = []string{"id", "notes", "created_at", "updated_at"}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lead, ok := s.leads[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "lead not found")
+		return
+	}
+	if !preconditionsMet(r, lead) {
+		writeError(w, http.StatusPreconditionFailed, "precondition_failed", "lead was modified")
+		return
+	}
+
+	updated, err := applyUpdate(lead, patch)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if fe := validateLead(updated); fe != nil {
+		writeValidationError(w, *fe)
+		return
+	}
+
+	updated.UpdatedAt = leadsdb.UnixTime{Time: time.Now().Truncate(time.Second)}
+	s.leads[updated.ID] = updated
+
+	w.Header().Set("ETag", leadETag(updated))
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// preconditionsMet evaluates the If-Match and If-Unmodified-Since headers of r
+// against lead. If-Unmodified-Since is ignored when If-Match is present.
+func preconditionsMet(r *http.Request, lead *leadsdb.Lead) bool {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		etag := leadETag(lead)
+		for _, candidate := range strings.Split(ifMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Unmodified-Since"); since != "" {
+		t, err := http.ParseTime(since)
+		if err == nil && lead.UpdatedAt.After(t) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// patchOps are the incremental operations accepted by PATCH /leads/{id}.
+type patchOps struct {
+	AddTags          []string            `json:"add_tags"`
+	RemoveTags       []string            `json:"remove_tags"`
+	SetAttributes    []leadsdb.Attribute `json:"set_attributes"`
+	DeleteAttributes []string            `json:"delete_attributes"`
+}
+
+// applyUpdate applies an update request body to a copy of lead: a merge patch
+// of its fields followed by the incremental tag and attribute operations.
+func applyUpdate(lead *leadsdb.Lead, patch map[string]json.RawMessage) (*leadsdb.Lead, error) {
+	var ops patchOps
+	for _, name := range []string{"add_tags", "remove_tags", "set_attributes", "delete_attributes"} {
+		if value, ok := patch[name]; ok {
+			if err := json.Unmarshal(fmt.Appendf(nil, "{%q:%s}", name, value), &ops); err != nil {
+				return nil, err
+			}
+			delete(patch, name)
+		}
+	}
+	for _, name := range readOnlyFields {
+		delete(patch, name)
+	}
+
+	updated, err := mergePatch(lead, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range ops.AddTags {
+		if !slices.Contains(updated.Tags, tag) {
+			updated.Tags = append(updated.Tags, tag)
+		}
+	}
+	updated.Tags = slices.DeleteFunc(updated.Tags, func(tag string) bool {
+		return slices.Contains(ops.RemoveTags, tag)
+	})
+
+	for _, attr := range ops.SetAttributes {
+		i := slices.IndexFunc(updated.Attributes, func(a leadsdb.Attribute) bool { return a.Name == attr.Name })
+		if i < 0 {
+			updated.Attributes = append(updated.Attributes, attr)
+		} else {
+			updated.Attributes[i] = attr
+		}
+	}
+	updated.Attributes = slices.DeleteFunc(updated.Attributes, func(a leadsdb.Attribute) bool {
+		return slices.Contains(ops.DeleteAttributes, a.Name)
+	})
+
+	return updated, nil
+}
+
+// mergePatch applies patch to a copy of lead with JSON Merge Patch semantics
+// at the top level: present keys replace the field and null clears it.
+func mergePatch(lead *leadsdb.Lead, patch map[string]json.RawMessage) (*leadsdb.Lead, error) {
+	data, err := json.Marshal(lead)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for name, value := range patch {
+		if string(value) == "null" {
+			delete(fields, name)
+			continue
+		}
+		fields[name] = value
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var out leadsdb.Lead
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	ok := s.deleteLead(id)
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "lead not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteLead removes a lead and its notes, reporting whether it existed.
+// The caller must hold s.mu.
+func (s *Server) deleteLead(id string) bool {
+	if _, ok := s.leads[id]; !ok {
+		return false
+	}
+
+	delete(s.leads, id)
+	s.order = slices.DeleteFunc(s.order, func(v string) bool { return v == id })
+	for noteID, note := range s.notes {
+		if note.LeadID == id {
+			delete(s.notes, noteID)
+		}
+	}
+	return true
+}
+
+// leadNotes returns the notes of a lead ordered by creation.
+// The caller must hold s.mu.
+func (s *Server) leadNotes(leadID string) []leadsdb.Note {
+	notes := []leadsdb.Note{}
+	for _, note := range s.notes {
+		if note.LeadID == leadID {
+			notes = append(notes, *note)
+		}
+	}
+
+	slices.SortFunc(notes, func(a, b leadsdb.Note) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt.Time), strings.Compare(a.ID, b.ID))
+	})
+
+	return notes
+}
+
+func (s *Server) handleListNotes(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	_, ok := s.leads[r.PathValue("id")]
+	var notes []leadsdb.Note
+	if ok {
+		notes = s.leadNotes(r.PathValue("id"))
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "lead not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, notes)
+}
+
+type noteRequest struct {
+	Content string `json:"content"`
+}
+
+func (s *Server) handleCreateNote(w http.ResponseWriter, r *http.Request) {
+	var body noteRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if body.Content == "" {
+		writeValidationError(w, leadsdb.FieldError{Field: "content", Rule: "required", Message: "is required"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	leadID := r.PathValue("id")
+	if _, ok := s.leads[leadID]; !ok {
+		writeError(w, http.StatusNotFound, "not_found", "lead not found")
+		return
+	}
+
+	s.nextID++
+	now := leadsdb.UnixTime{Time: time.Now().Truncate(time.Second)}
+	note := &leadsdb.Note{
+		ID:        "note_" + strconv.Itoa(s.nextID),
+		LeadID:    leadID,
+		Content:   body.Content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.notes[note.ID] = note
+
+	writeJSON(w, http.StatusCreated, note)
+}
+
+func (s *Server) handleUpdateNote(w http.ResponseWriter, r *http.Request) {
+	var body noteRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return
+	}
+	if body.Content == "" {
+		writeValidationError(w, leadsdb.FieldError{Field: "content", Rule: "required", Message: "is required"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	note, ok := s.notes[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "note not found")
+		return
+	}
+
+	note.Content = body.Content
+	note.UpdatedAt = leadsdb.UnixTime{Time: time.Now().Truncate(time.Second)}
+
+	writeJSON(w, http.StatusOK, note)
+}
+
+func (s *Server) handleDeleteNote(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	_, ok := s.notes[r.PathValue("id")]
+	delete(s.notes, r.PathValue("id"))
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "note not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,36 @@
+package leadsdbtest
+
+import (
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/internal/query"
+)
+
+// record returns the fields of lead that filters and sorting read.
+func record(lead *leadsdb.Lead) *query.Record {
+	r := &query.Record{
+		Name:        lead.Name,
+		Source:      lead.Source,
+		Description: lead.Description,
+		Address:     lead.Address,
+		City:        lead.City,
+		State:       lead.State,
+		Country:     lead.Country,
+		PostalCode:  lead.PostalCode,
+		Phone:       lead.Phone,
+		Email:       lead.Email,
+		Website:     lead.Website,
+		Category:    lead.Category,
+		SourceID:    lead.SourceID,
+		LogoURL:     lead.LogoURL,
+		Rating:      lead.Rating,
+		ReviewCount: lead.ReviewCount,
+		Tags:        lead.Tags,
+	}
+	if lead.Coordinates != nil {
+		r.Coordinates = &query.Coordinate{Latitude: lead.Coordinates.Latitude, Longitude: lead.Coordinates.Longitude}
+	}
+	for _, a := range lead.Attributes {
+		r.Attributes = append(r.Attributes, query.Attribute{Name: a.Name, Value: a.Value})
+	}
+	return r
+}
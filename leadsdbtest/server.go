@@ -0,0 +1,477 @@
+// Package leadsdbtest provides an in-memory fake of the LeadsDB API for tests.
+//
+// The fake implements every endpoint used by leadsdb.Client, including
+// filtering, sorting and cursor pagination, and can be told to fail requests
+// with arbitrary status codes to exercise retry paths.
+//
+// POST requests carrying an Idempotency-Key header are handled once; repeats
+// with the same key receive the original response.
+//
+// Leads are served with an ETag, and updates honour the If-Match and
+// If-Unmodified-Since preconditions, failing with 412 Precondition Failed.
+package leadsdbtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosom/go-leadsdb"
+)
+
+const (
+	// DefaultPageSize is the page size used when a list request has no limit.
+	DefaultPageSize = 50
+	// MaxPageSize is the largest page size the server returns.
+	MaxPageSize = 100
+	// maxBatchSize is the maximum number of leads, updates or IDs accepted by /leads/batch.
+	maxBatchSize = 100
+)
+
+// Server is an in-memory LeadsDB API server.
+type Server struct {
+	*httptest.Server
+
+	apiKey string
+
+	mu       sync.Mutex
+	leads    map[string]*leadsdb.Lead
+	order    []string
+	notes    map[string]*leadsdb.Note
+	nextID   int
+	faults   []*Fault
+	requests []Request
+	replies  map[string]*reply
+}
+
+// Option configures the Server.
+type Option func(*Server)
+
+// WithAPIKey makes the server reject requests whose X-API-Key header does not match key.
+func WithAPIKey(key string) Option {
+	return func(s *Server) {
+		s.apiKey = key
+	}
+}
+
+// NewServer starts a new in-memory LeadsDB server.
+// The caller must call Close when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		leads:   make(map[string]*leadsdb.Lead),
+		notes:   make(map[string]*leadsdb.Note),
+		replies: make(map[string]*reply),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.Server = httptest.NewServer(s.routes())
+
+	return s
+}
+
+// Client returns a leadsdb.Client configured to talk to the server.
+func (s *Server) Client(opts ...leadsdb.Option) *leadsdb.Client {
+	opts = append([]leadsdb.Option{leadsdb.WithBaseURL(s.URL)}, opts...)
+	return leadsdb.New(s.apiKey, opts...)
+}
+
+// NoDelay is a leadsdb.RetryPolicy that retries like leadsdb.DefaultRetryPolicy
+// without waiting between attempts, so tests of retry paths run quickly:
+//
+//	client := srv.Client(leadsdb.WithRetryPolicy(leadsdbtest.NoDelay{}))
+type NoDelay struct{ leadsdb.DefaultRetryPolicy }
+
+// Delay implements leadsdb.RetryPolicy.
+func (NoDelay) Delay(int, time.Duration) time.Duration { return 0 }
+
+// Fault describes an error response returned instead of handling a request.
+type Fault struct {
+	// Method restricts the fault to requests with this HTTP method. Empty matches any method.
+	Method string
+	// Path restricts the fault to requests with this URL path. Empty matches any path.
+	Path string
+	// Status is the HTTP status code to respond with.
+	Status int
+	// Code is the API error code included in the response body.
+	Code string
+	// RetryAfter is sent as the Retry-After header when non-empty.
+	RetryAfter string
+	// Times is the number of requests the fault applies to. Zero means once.
+	Times int
+	// TruncateAfter, when positive, lets the request through but drops the
+	// connection after this many bytes of the response body, simulating an
+	// interrupted download. Status, Code and RetryAfter are ignored.
+	TruncateAfter int
+	// Delay, when positive, lets the request through but holds it this long
+	// before it is handled, simulating a slow server. Status, Code and
+	// RetryAfter are ignored. It can be combined with TruncateAfter.
+	Delay time.Duration
+}
+
+// InjectFault queues a fault. Faults are matched in the order they were injected.
+func (s *Server) InjectFault(f Fault) {
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// FailNext makes the next n requests fail with the given status code.
+func (s *Server) FailNext(status, n int) {
+	s.InjectFault(Fault{Status: status, Times: n})
+}
+
+// Request is a request received by the server.
+type Request struct {
+	// ID is the request ID sent back in the X-Request-Id header.
+	ID     string
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+}
+
+// Requests returns all requests received by the server, including failed ones.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// AddLead stores a lead directly, bypassing the API, and returns the stored copy.
+// An ID and timestamps are assigned when missing.
+func (s *Server) AddLead(lead leadsdb.Lead) *leadsdb.Lead {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.insertLead(&lead)
+	out := *stored
+	return &out
+}
+
+// Leads returns a snapshot of all stored leads in insertion order.
+func (s *Server) Leads() []leadsdb.Lead {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	leads := make([]leadsdb.Lead, 0, len(s.order))
+	for _, id := range s.order {
+		leads = append(leads, *s.leads[id])
+	}
+	return leads
+}
+
+// Reset removes all leads, notes, faults and recorded requests.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leads = make(map[string]*leadsdb.Lead)
+	s.order = nil
+	s.notes = make(map[string]*leadsdb.Note)
+	s.faults = nil
+	s.requests = nil
+	s.replies = make(map[string]*reply)
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /leads", s.handleList)
+	mux.HandleFunc("POST /leads", s.handleCreate)
+	mux.HandleFunc("POST /leads/batch", s.handleBulkCreate)
+	mux.HandleFunc("PATCH /leads/batch", s.handleBulkUpdate)
+	mux.HandleFunc("POST /leads/batch/delete", s.handleBulkDelete)
+	mux.HandleFunc("POST /leads/export", s.handleExport)
+	mux.HandleFunc("GET /leads/{id}", s.handleGet)
+	mux.HandleFunc("PATCH /leads/{id}", s.handleUpdate)
+	mux.HandleFunc("DELETE /leads/{id}", s.handleDelete)
+	mux.HandleFunc("GET /leads/{id}/notes", s.handleListNotes)
+	mux.HandleFunc("POST /leads/{id}/notes", s.handleCreateNote)
+	mux.HandleFunc("PUT /leads/notes/{id}", s.handleUpdateNote)
+	mux.HandleFunc("DELETE /leads/notes/{id}", s.handleDeleteNote)
+
+	return s.middleware(mux)
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		id := "req_" + strconv.Itoa(len(s.requests)+1)
+		s.requests = append(s.requests, Request{
+			ID:     id,
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+		})
+		fault := s.takeFault(r)
+		s.mu.Unlock()
+
+		w.Header().Set("X-Request-Id", id)
+
+		handler := next
+		switch {
+		case fault == nil:
+		case fault.Delay > 0 || fault.TruncateAfter > 0:
+			if fault.Delay > 0 {
+				handler = delayed(handler, fault.Delay)
+			}
+			if fault.TruncateAfter > 0 {
+				w = &truncatingWriter{ResponseWriter: w, remaining: fault.TruncateAfter}
+			}
+		default:
+			if fault.RetryAfter != "" {
+				w.Header().Set("Retry-After", fault.RetryAfter)
+			}
+			writeError(w, fault.Status, fault.Code, http.StatusText(fault.Status))
+			return
+		}
+
+		if s.apiKey != "" && r.Header.Get("X-API-Key") != s.apiKey {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "invalid API key")
+			return
+		}
+
+		if key := r.Header.Get("Idempotency-Key"); key != "" && r.Method == http.MethodPost {
+			s.serveIdempotent(w, r, key, handler)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// delayed returns a handler that waits for d before calling next.
+func delayed(next http.Handler, d time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(d):
+			next.ServeHTTP(w, r)
+		case <-r.Context().Done():
+		}
+	})
+}
+
+// reply is the response to an idempotent request. It is reserved before the
+// request is handled, so concurrent requests with the same key wait for it.
+type reply struct {
+	method string
+	path   string
+	// done is closed once the response below is recorded.
+	done   chan struct{}
+	status int
+	header http.Header
+	body   []byte
+}
+
+// serveIdempotent handles r once per key and replays the recorded response to
+// repeats, including ones that arrive while the first request is running.
+// The response is recorded before it is written, so a request whose connection
+// fails part way is still replayed. Server errors are not recorded, so a
+// repeat of a request that failed with 5xx is handled again.
+func (s *Server) serveIdempotent(w http.ResponseWriter, r *http.Request, key string, next http.Handler) {
+	for {
+		s.mu.Lock()
+		prev, ok := s.replies[key]
+		if !ok {
+			res := &reply{method: r.Method, path: r.URL.Path, done: make(chan struct{})}
+			s.replies[key] = res
+			s.mu.Unlock()
+
+			s.record(res, key, r, next)
+			res.writeTo(w)
+			return
+		}
+		s.mu.Unlock()
+
+		if prev.method != r.Method || prev.path != r.URL.Path {
+			writeError(w, http.StatusUnprocessableEntity, "idempotency_key_reused",
+				"idempotency key was used for a different request")
+			return
+		}
+
+		select {
+		case <-prev.done:
+		case <-r.Context().Done():
+			return
+		}
+		if prev.status < http.StatusInternalServerError {
+			prev.writeTo(w)
+			return
+		}
+	}
+}
+
+// record handles r into res and releases the key when the response is a
+// server error, or the handler panicked.
+func (s *Server) record(res *reply, key string, r *http.Request, next http.Handler) {
+	res.status = http.StatusInternalServerError
+	defer func() {
+		if res.status >= http.StatusInternalServerError {
+			s.mu.Lock()
+			if s.replies[key] == res {
+				delete(s.replies, key)
+			}
+			s.mu.Unlock()
+		}
+		close(res.done)
+	}()
+
+	rec := httptest.NewRecorder()
+	next.ServeHTTP(rec, r)
+
+	res.header = rec.Header().Clone()
+	res.body = rec.Body.Bytes()
+	res.status = rec.Code
+}
+
+func (res *reply) writeTo(w http.ResponseWriter) {
+	for k, v := range res.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(res.status)
+	_, _ = w.Write(res.body)
+}
+
+// truncatingWriter aborts the response once its byte budget is used up.
+type truncatingWriter struct {
+	http.ResponseWriter
+	remaining int
+}
+
+func (w *truncatingWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.remaining {
+		w.remaining -= len(p)
+		return w.ResponseWriter.Write(p)
+	}
+
+	_, _ = w.ResponseWriter.Write(p[:w.remaining])
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// takeFault returns the first fault matching r and consumes one use of it.
+// The caller must hold s.mu.
+func (s *Server) takeFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if f.Path != "" && f.Path != r.URL.Path {
+			continue
+		}
+
+		f.Times--
+		if f.Times <= 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+		return f
+	}
+	return nil
+}
+
+// insertLead assigns an ID and timestamps to lead and stores it.
+// The caller must hold s.mu.
+func (s *Server) insertLead(lead *leadsdb.Lead) *leadsdb.Lead {
+	stored := cloneLead(lead)
+
+	if stored.ID == "" {
+		s.nextID++
+		stored.ID = "lead_" + strconv.Itoa(s.nextID)
+	}
+
+	now := leadsdb.UnixTime{Time: time.Now().Truncate(time.Second)}
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = now
+	}
+	if stored.UpdatedAt.IsZero() {
+		stored.UpdatedAt = now
+	}
+	stored.Notes = nil
+
+	if _, exists := s.leads[stored.ID]; !exists {
+		s.order = append(s.order, stored.ID)
+	}
+	s.leads[stored.ID] = stored
+
+	return stored
+}
+
+// cloneLead returns a deep copy of lead, normalised through its JSON encoding.
+func cloneLead(lead *leadsdb.Lead) *leadsdb.Lead {
+	data, err := json.Marshal(lead)
+	if err != nil {
+		panic("leadsdbtest: encoding lead: " + err.Error())
+	}
+
+	var out leadsdb.Lead
+	if err := json.Unmarshal(data, &out); err != nil {
+		panic("leadsdbtest: decoding lead: " + err.Error())
+	}
+
+	return &out
+}
+
+// leadETag returns the entity tag of the stored state of lead.
+func leadETag(lead *leadsdb.Lead) string {
+	stored := *lead
+	stored.Notes = nil
+
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		panic("leadsdbtest: encoding lead: " + err.Error())
+	}
+	sum := sha256.Sum256(data)
+
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func validateLead(lead *leadsdb.Lead) *leadsdb.FieldError {
+	switch {
+	case strings.TrimSpace(lead.Name) == "":
+		return &leadsdb.FieldError{Field: "name", Rule: "required", Message: "is required"}
+	case strings.TrimSpace(lead.Source) == "":
+		return &leadsdb.FieldError{Field: "source", Rule: "required", Message: "is required"}
+	default:
+		return nil
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]string{
+		"code":    code,
+		"message": message,
+	})
+}
+
+func writeValidationError(w http.ResponseWriter, fields ...leadsdb.FieldError) {
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"code":    "validation_error",
+		"message": "validation failed",
+		"details": fields,
+	})
+}
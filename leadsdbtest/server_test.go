@@ -0,0 +1,273 @@
+package leadsdbtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+// send makes a raw request to the server and returns the response with its body read.
+func send(t *testing.T, srv *leadsdbtest.Server, method, path, body string, header http.Header) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: reading body: %v", method, path, err)
+	}
+	return resp, data
+}
+
+func TestInjectFault(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	srv.InjectFault(leadsdbtest.Fault{
+		Method:     http.MethodGet,
+		Path:       "/leads",
+		Status:     http.StatusServiceUnavailable,
+		Code:       "maintenance",
+		RetryAfter: "7",
+		Times:      2,
+	})
+
+	// Requests with another method or path are not affected.
+	if resp, _ := send(t, srv, http.MethodGet, "/leads/missing", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET /leads/missing: status %d, want 404", resp.StatusCode)
+	}
+	if resp, _ := send(t, srv, http.MethodPost, "/leads", `{"name":"Acme","source":"test"}`, nil); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /leads: status %d, want 201", resp.StatusCode)
+	}
+
+	for range 2 {
+		resp, body := send(t, srv, http.MethodGet, "/leads", "", nil)
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("status %d, want 503", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Retry-After"); got != "7" {
+			t.Errorf("Retry-After = %q, want 7", got)
+		}
+		var apiErr struct{ Code string }
+		if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Code != "maintenance" {
+			t.Errorf("body %s, want code maintenance", body)
+		}
+	}
+
+	if resp, _ := send(t, srv, http.MethodGet, "/leads", "", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d after the fault was used up, want 200", resp.StatusCode)
+	}
+}
+
+func TestFaultTruncateAfter(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	srv.AddLead(leadsdb.Lead{Name: "Acme", Source: "test"})
+	srv.InjectFault(leadsdbtest.Fault{Path: "/leads", TruncateAfter: 10})
+
+	resp, err := http.Get(srv.URL + "/leads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Fatalf("read %q without error, want the connection to be dropped", body)
+	}
+	if len(body) != 10 {
+		t.Errorf("read %d bytes, want 10", len(body))
+	}
+}
+
+func TestRequestsAreRecorded(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	srv.FailNext(http.StatusInternalServerError, 1)
+	failed, _ := send(t, srv, http.MethodGet, "/leads?limit=5", "", nil)
+	ok, _ := send(t, srv, http.MethodGet, "/leads", "", http.Header{"X-Trace": {"abc"}})
+
+	reqs := srv.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("recorded %d requests, want 2", len(reqs))
+	}
+	if got := failed.Header.Get("X-Request-Id"); got == "" || got != reqs[0].ID {
+		t.Errorf("X-Request-Id = %q, want %q", got, reqs[0].ID)
+	}
+	if got := ok.Header.Get("X-Request-Id"); got != reqs[1].ID || reqs[0].ID == reqs[1].ID {
+		t.Errorf("X-Request-Id = %q, want a new ID %q", got, reqs[1].ID)
+	}
+	if r := reqs[0]; r.Method != http.MethodGet || r.Path != "/leads" || r.Query.Get("limit") != "5" {
+		t.Errorf("recorded %+v, want GET /leads?limit=5", r)
+	}
+	if got := reqs[1].Header.Get("X-Trace"); got != "abc" {
+		t.Errorf("recorded header X-Trace = %q, want abc", got)
+	}
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	key := http.Header{"Idempotency-Key": {"key-1"}}
+	body := `{"name":"Acme","source":"test"}`
+
+	first, firstBody := send(t, srv, http.MethodPost, "/leads", body, key)
+	second, secondBody := send(t, srv, http.MethodPost, "/leads", body, key)
+	if first.StatusCode != http.StatusCreated || second.StatusCode != http.StatusCreated {
+		t.Fatalf("statuses %d and %d, want 201", first.StatusCode, second.StatusCode)
+	}
+	if string(firstBody) != string(secondBody) {
+		t.Errorf("replayed %s, want %s", secondBody, firstBody)
+	}
+	if got := len(srv.Leads()); got != 1 {
+		t.Fatalf("stored %d leads, want 1", got)
+	}
+
+	resp, _ := send(t, srv, http.MethodPost, "/leads/batch", `{"leads":[]}`, key)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("key reused on another path: status %d, want 422", resp.StatusCode)
+	}
+}
+
+func TestIdempotentConcurrentRequests(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	// Hold the first request so that the others arrive while it is running.
+	srv.InjectFault(leadsdbtest.Fault{Method: http.MethodPost, Delay: 100 * time.Millisecond})
+
+	const n = 20
+	bodies := make([][]byte, n)
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			<-start
+			resp, body := send(t, srv, http.MethodPost, "/leads", `{"name":"Acme","source":"test"}`,
+				http.Header{"Idempotency-Key": {"same-key"}})
+			if resp.StatusCode != http.StatusCreated {
+				t.Errorf("status %d, want 201", resp.StatusCode)
+			}
+			bodies[i] = body
+		})
+	}
+	close(start)
+	wg.Wait()
+
+	if got := len(srv.Leads()); got != 1 {
+		t.Fatalf("stored %d leads, want 1", got)
+	}
+	for i, body := range bodies {
+		if string(body) != string(bodies[0]) {
+			t.Fatalf("response %d is %s, want the replayed %s", i, body, bodies[0])
+		}
+	}
+}
+
+func TestClientRetriesFaults(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	client := srv.Client(leadsdb.WithRetryPolicy(leadsdbtest.NoDelay{}))
+	ctx := context.Background()
+
+	srv.FailNext(http.StatusServiceUnavailable, 1)
+	// The response to the first successful attempt is lost, so the client
+	// retries and the server replays it.
+	srv.InjectFault(leadsdbtest.Fault{Method: http.MethodPost, Path: "/leads", TruncateAfter: 5})
+
+	lead, err := client.Create(ctx, &leadsdb.Lead{Name: "Acme", Source: "test"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	stored := srv.Leads()
+	if len(stored) != 1 || stored[0].ID != lead.ID {
+		t.Fatalf("stored %+v, want only %s", stored, lead.ID)
+	}
+
+	reqs := srv.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("recorded %d requests, want 3", len(reqs))
+	}
+	key := reqs[0].Header.Get("Idempotency-Key")
+	for _, r := range reqs {
+		if r.Header.Get("Idempotency-Key") != key || key == "" {
+			t.Fatalf("attempts sent Idempotency-Key %q and %q, want the same key", key, r.Header.Get("Idempotency-Key"))
+		}
+	}
+
+	srv.FailNext(http.StatusServiceUnavailable, 10)
+	_, err = client.Get(ctx, lead.ID)
+	var apiErr *leadsdb.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got %v, want a 503 APIError once retries run out", err)
+	}
+}
+
+func TestWithAPIKey(t *testing.T) {
+	srv := leadsdbtest.NewServer(leadsdbtest.WithAPIKey("secret"))
+	defer srv.Close()
+
+	if resp, _ := send(t, srv, http.MethodGet, "/leads", "", http.Header{"X-Api-Key": {"wrong"}}); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong key: status %d, want 401", resp.StatusCode)
+	}
+	if _, err := srv.Client().List(context.Background()); err != nil {
+		t.Fatalf("List with the server's key: %v", err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	srv.AddLead(leadsdb.Lead{Name: "Acme", Source: "test"})
+	send(t, srv, http.MethodPost, "/leads", `{"name":"Globex","source":"test"}`, http.Header{"Idempotency-Key": {"key-1"}})
+	srv.FailNext(http.StatusServiceUnavailable, 5)
+
+	srv.Reset()
+
+	if got := len(srv.Leads()); got != 0 {
+		t.Errorf("%d leads after Reset, want 0", got)
+	}
+	if got := len(srv.Requests()); got != 0 {
+		t.Errorf("%d requests after Reset, want 0", got)
+	}
+
+	// Faults and recorded replies are gone too.
+	resp, _ := send(t, srv, http.MethodPost, "/leads", `{"name":"Globex","source":"test"}`, http.Header{"Idempotency-Key": {"key-1"}})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status %d after Reset, want 201", resp.StatusCode)
+	}
+	if got := len(srv.Leads()); got != 1 {
+		t.Errorf("%d leads, want the lead to be created again", got)
+	}
+}
@@ -0,0 +1,72 @@
+package leadsdb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// WithLogger logs API calls to l.
+//
+// Every attempt and successful response is logged at debug level, including
+// request headers with the API key redacted. Retries and an open circuit
+// breaker are logged as warnings with the status, error code and delay.
+// Requests that finally fail are logged as errors, except for client errors
+// such as 404 Not Found, which are logged at info level.
+// A nil logger disables logging, which is the default.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) {
+		if l == nil {
+			l = slog.New(slog.DiscardHandler)
+		}
+		c.logger = l
+	}
+}
+
+// logFailure logs a request that failed with err and will not be retried.
+func (c *Client) logFailure(ctx context.Context, attrs []slog.Attr, err error) {
+	level := slog.LevelError
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError && apiErr.StatusCode != http.StatusTooManyRequests {
+		level = slog.LevelInfo
+	}
+
+	c.logger.LogAttrs(ctx, level, "leadsdb: request failed", append(attrs, errorAttrs(err)...)...)
+}
+
+// errorAttrs describes err, including the status and code of an *APIError.
+func errorAttrs(err error) []slog.Attr {
+	attrs := []slog.Attr{slog.String("error", err.Error())}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		attrs = append(attrs, slog.Int("status", apiErr.StatusCode))
+		if apiErr.Code != "" {
+			attrs = append(attrs, slog.String("code", apiErr.Code))
+		}
+	}
+
+	return attrs
+}
+
+// logHeader logs request headers with credentials redacted.
+type logHeader http.Header
+
+// LogValue implements slog.LogValuer.
+func (h logHeader) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(h))
+	for _, key := range slices.Sorted(maps.Keys(h)) {
+		value := strings.Join(h[key], ", ")
+		switch http.CanonicalHeaderKey(key) {
+		case "X-Api-Key", "Authorization":
+			value = "REDACTED"
+		}
+		attrs = append(attrs, slog.String(key, value))
+	}
+	return slog.GroupValue(attrs...)
+}
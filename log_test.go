@@ -0,0 +1,77 @@
+package leadsdb_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestLoggerRedactsCredentials(t *testing.T) {
+	const apiKey, token = "secret-key-123", "Bearer secret-token-456"
+
+	handlers := map[string]func(io.Writer) slog.Handler{
+		"text": func(w io.Writer) slog.Handler {
+			return slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+		},
+		"json": func(w io.Writer) slog.Handler {
+			return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+		},
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			srv := leadsdbtest.NewServer(leadsdbtest.WithAPIKey(apiKey))
+			defer srv.Close()
+
+			authorize := func(next leadsdb.Doer) leadsdb.Doer {
+				return leadsdb.DoerFunc(func(ctx context.Context, req *leadsdb.Request) (*leadsdb.Response, error) {
+					req.Header.Set("Authorization", token)
+					return next.Do(ctx, req)
+				})
+			}
+
+			var buf bytes.Buffer
+			client := srv.Client(
+				leadsdb.WithLogger(slog.New(handler(&buf))),
+				leadsdb.WithRetryPolicy(leadsdbtest.NoDelay{}),
+				leadsdb.WithMiddleware(authorize),
+			)
+			ctx := context.Background()
+
+			srv.FailNext(http.StatusServiceUnavailable, 1)
+			lead, err := client.Create(ctx, &leadsdb.Lead{Name: "Acme", Source: "test"})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if _, err := client.Get(ctx, "missing"); err == nil {
+				t.Fatal("Get succeeded, want not found")
+			}
+			srv.FailNext(http.StatusInternalServerError, 10)
+			if _, err := client.Get(ctx, lead.ID); err == nil {
+				t.Fatal("Get succeeded, want a server error")
+			}
+
+			out := buf.String()
+			for _, secret := range []string{apiKey, "secret-token-456"} {
+				if strings.Contains(out, secret) {
+					t.Fatalf("log output contains %q:\n%s", secret, out)
+				}
+			}
+			for _, want := range []string{"REDACTED", "leadsdb: sending request", "leadsdb: retrying request", "leadsdb: request failed"} {
+				if !strings.Contains(out, want) {
+					t.Fatalf("log output lacks %q:\n%s", want, out)
+				}
+			}
+			if got := strings.Count(out, "REDACTED"); got < 2*len(srv.Requests()) {
+				t.Fatalf("got %d redacted values for %d requests, want both headers redacted in each", got, len(srv.Requests()))
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package leadsdb
+
+import "github.com/gosom/go-leadsdb/internal/query"
+
+// Match reports whether lead satisfies the filters in opts, using the same
+// semantics as the API: every AND filter must match and, when OR filters are
+// present, at least one of them must match. Filter groups must match as well.
+//
+// Text comparisons are case-insensitive. Numeric filters never match a lead
+// whose field is unset. Options other than filters, such as Limit or Sort, are ignored.
+func Match(lead *Lead, opts ...ListOption) bool {
+	if lead == nil {
+		return false
+	}
+
+	cfg := &listConfig{}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+
+	filters := make([]query.Filter, 0, len(cfg.filters))
+	for _, f := range cfg.filters {
+		filters = append(filters, f.query())
+	}
+	groups := make([]query.Group, 0, len(cfg.groups))
+	for _, g := range cfg.groups {
+		groups = append(groups, g.query())
+	}
+
+	return query.Match(lead.record(), filters, groups)
+}
+
+// String returns the query parameter encoding of the filter.
+func (f FilterOption) String() string {
+	return f.filter.String()
+}
+
+func (f filter) query() query.Filter {
+	return query.Filter{Logic: string(f.logic), Operator: f.operator, Field: f.field, Value: f.value}
+}
+
+func (n node) query() query.Group {
+	g := query.Group{Op: string(n.Op), Field: n.Field, Operator: n.Operator, Value: n.Value}
+	for _, c := range n.Conditions {
+		g.Conditions = append(g.Conditions, c.query())
+	}
+	return g
+}
+
+// record returns the fields of l that filters read.
+func (l *Lead) record() *query.Record {
+	r := &query.Record{
+		Name:        l.Name,
+		Source:      l.Source,
+		Description: l.Description,
+		Address:     l.Address,
+		City:        l.City,
+		State:       l.State,
+		Country:     l.Country,
+		PostalCode:  l.PostalCode,
+		Phone:       l.Phone,
+		Email:       l.Email,
+		Website:     l.Website,
+		Category:    l.Category,
+		SourceID:    l.SourceID,
+		LogoURL:     l.LogoURL,
+		Rating:      l.Rating,
+		ReviewCount: l.ReviewCount,
+		Tags:        l.Tags,
+	}
+	if l.Coordinates != nil {
+		r.Coordinates = &query.Coordinate{Latitude: l.Coordinates.Latitude, Longitude: l.Coordinates.Longitude}
+	}
+	for _, a := range l.Attributes {
+		r.Attributes = append(r.Attributes, query.Attribute{Name: a.Name, Value: a.Value})
+	}
+	return r
+}
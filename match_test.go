@@ -0,0 +1,62 @@
+package leadsdb_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestListFiltersMatchLocally(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	leads := []leadsdb.Lead{
+		{Name: "Acme", Source: "test", City: "Berlin", Rating: leadsdb.Ptr(4.5), Tags: []string{"vip"},
+			Attributes: []leadsdb.Attribute{{Name: "size", Value: 120.0}}},
+		{Name: "Globex", Source: "test", City: "Munich", Rating: leadsdb.Ptr(3.0),
+			Attributes: []leadsdb.Attribute{{Name: "size", Value: 15.0}}},
+		{Name: "Initech", Source: "test", City: "berlin",
+			Coordinates: &leadsdb.Coordinate{Latitude: 52.52, Longitude: 13.405}},
+	}
+	for _, lead := range leads {
+		srv.AddLead(lead)
+	}
+	client := srv.Client()
+
+	tests := []struct {
+		name string
+		opts []leadsdb.ListOption
+		want []string
+	}{
+		{"text", []leadsdb.ListOption{leadsdb.City().Eq("BERLIN")}, []string{"Acme", "Initech"}},
+		{"number", []leadsdb.ListOption{leadsdb.Rating().Gte(4)}, []string{"Acme"}},
+		{"or", []leadsdb.ListOption{leadsdb.Or().City().Eq("Munich"), leadsdb.Or().Tags().Contains("VIP")}, []string{"Acme", "Globex"}},
+		{"attr", []leadsdb.ListOption{leadsdb.Attr("size").Gt(100)}, []string{"Acme"}},
+		{"radius", []leadsdb.ListOption{leadsdb.Location().WithinRadius(52.5, 13.4, 10)}, []string{"Initech"}},
+		{"group", []leadsdb.ListOption{leadsdb.Not(leadsdb.AnyOf(leadsdb.City().Eq("Munich"), leadsdb.Tags().Contains("vip")))}, []string{"Initech"}},
+		{"sort by attr", []leadsdb.ListOption{leadsdb.Attr("size").Gt(0), leadsdb.Sort(leadsdb.AttrSortField("size"), leadsdb.Asc)}, []string{"Globex", "Acme"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.List(context.Background(), tt.opts...)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+
+			var got []string
+			for i := range result.Leads {
+				got = append(got, result.Leads[i].Name)
+				if !leadsdb.Match(&result.Leads[i], tt.opts...) {
+					t.Errorf("Match(%s) = false for a lead the server returned", result.Leads[i].Name)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
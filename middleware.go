@@ -0,0 +1,66 @@
+package leadsdb
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Request is an API call as seen by middleware.
+type Request struct {
+	// Method is the HTTP method.
+	Method string
+	// Path is the request path relative to the base URL, including any query string.
+	Path string
+	// Body is the value sent as the JSON request body, or nil.
+	Body any
+	// Header holds headers added to the request. Middleware may add its own.
+	Header http.Header
+
+	// stream leaves the response body unread, for exports.
+	stream bool
+}
+
+// Response is the successful result of an API call as seen by middleware.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	// Body is the response body. Middleware that reads it must replace it with
+	// a reader returning the same content.
+	Body io.ReadCloser
+}
+
+// Doer performs API calls.
+type Doer interface {
+	// Do performs req, including any retries, and returns the response.
+	// Unsuccessful API responses are returned as an *APIError.
+	Do(ctx context.Context, req *Request) (*Response, error)
+}
+
+// DoerFunc adapts a function to the Doer interface.
+type DoerFunc func(ctx context.Context, req *Request) (*Response, error)
+
+// Do calls f(ctx, req).
+func (f DoerFunc) Do(ctx context.Context, req *Request) (*Response, error) {
+	return f(ctx, req)
+}
+
+// Middleware wraps a Doer to observe or modify API calls.
+type Middleware func(next Doer) Doer
+
+// WithMiddleware adds middleware around every API call made by the client,
+// including exports. Each call passes through the middleware once, however
+// many times it is retried. The first middleware given is the outermost.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+// chain wraps next in mw, with mw[0] outermost.
+func chain(mw []Middleware, next Doer) Doer {
+	for i := len(mw) - 1; i >= 0; i-- {
+		next = mw[i](next)
+	}
+	return next
+}
@@ -0,0 +1,90 @@
+package leadsdb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestMiddleware(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	var events []string
+	var errs []error
+	trace := func(name string) leadsdb.Middleware {
+		return func(next leadsdb.Doer) leadsdb.Doer {
+			return leadsdb.DoerFunc(func(ctx context.Context, req *leadsdb.Request) (*leadsdb.Response, error) {
+				events = append(events, name+" "+req.Method+" "+req.Path)
+				req.Header.Add("X-Trace", name)
+				resp, err := next.Do(ctx, req)
+				events = append(events, name+" done")
+				if name == "inner" {
+					errs = append(errs, err)
+				}
+				return resp, err
+			})
+		}
+	}
+
+	client := srv.Client(
+		leadsdb.WithRetryPolicy(leadsdbtest.NoDelay{}),
+		leadsdb.WithMiddleware(trace("outer")),
+		leadsdb.WithMiddleware(trace("inner")),
+	)
+	ctx := context.Background()
+
+	// Two failed attempts and a successful retry are one call for middleware.
+	srv.FailNext(http.StatusServiceUnavailable, 2)
+	lead, err := client.Create(ctx, &leadsdb.Lead{Name: "Acme", Source: "test"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	want := []string{"outer POST /leads", "inner POST /leads", "inner done", "outer done"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("got events %q, want %q", events, want)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("server received %d requests, want 3", len(reqs))
+	}
+	for _, r := range reqs {
+		if got := r.Header.Values("X-Trace"); !slices.Equal(got, []string{"outer", "inner"}) {
+			t.Fatalf("attempt sent X-Trace %q, want [outer inner]", got)
+		}
+	}
+
+	// Exports pass through the middleware too.
+	events = nil
+	export, err := client.Export(ctx, leadsdb.ExportJSON)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if _, err := io.ReadAll(export); err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	export.Close()
+	if len(events) != 4 || !strings.HasPrefix(events[0], "outer POST /leads/export") {
+		t.Fatalf("got events %q, want the export to pass through both middleware", events)
+	}
+
+	// Middleware sees the final error once.
+	errs = nil
+	srv.FailNext(http.StatusServiceUnavailable, 10)
+	_, err = client.Get(ctx, lead.ID)
+	if len(errs) != 1 || !errors.Is(errs[0], err) {
+		t.Fatalf("middleware saw errors %v, want only the final error %v", errs, err)
+	}
+	var apiErr *leadsdb.APIError
+	if !errors.As(errs[0], &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("middleware saw %v, want the 503 APIError", errs[0])
+	}
+}
@@ -0,0 +1,147 @@
+package leadsdb
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WithRateLimit limits the client to rps requests per second with bursts of up
+// to burst requests. The limit is shared by every call made through the client,
+// including iterators, exports and bulk operations, and retries count against it.
+//
+// The limiter also follows the server: when a response reports that no requests
+// remain through X-RateLimit-Remaining, or asks the client to back off with
+// Retry-After, all requests wait until X-RateLimit-Reset or the Retry-After delay
+// has passed. A non-positive rps disables rate limiting.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *Client) {
+		if rps <= 0 {
+			c.limiter = nil
+			return
+		}
+		c.limiter = newRateLimiter(rps, burst)
+	}
+}
+
+// rateLimiter is a token bucket. Tokens may go negative, in which case callers
+// wait in turn for the bucket to refill.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	// now returns the current time. Tests replace it to control the clock.
+	now func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	// until pauses all requests until this time, as requested by the server.
+	until time.Time
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	burst = max(burst, 1)
+	return &rateLimiter{
+		rate:   rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// wait blocks until a request may be sent or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		// Return the unused token.
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.refill(now)
+	l.tokens--
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	if pause := l.until.Sub(now); pause > delay {
+		delay = pause
+	}
+	return delay
+}
+
+// refill adds the tokens accumulated since the last call. The caller must hold l.mu.
+func (l *rateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+	l.tokens = min(l.tokens+elapsed*l.rate, l.burst)
+}
+
+// observe adapts the limiter to the rate limit headers of a response.
+func (l *rateLimiter) observe(resp *http.Response) {
+	now := l.now()
+
+	var until time.Time
+	if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok && retryableStatus(resp.StatusCode) {
+		until = now.Add(d)
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	hasRemaining := err == nil
+
+	if hasRemaining && remaining <= 0 {
+		if reset, ok := parseRateLimitReset(resp.Header.Get("X-RateLimit-Reset"), now); ok && reset.After(until) {
+			until = reset
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until.After(l.until) {
+		l.until = until
+	}
+	if hasRemaining {
+		l.refill(now)
+		// The server knows about requests made by other clients sharing the key.
+		l.tokens = min(l.tokens, float64(max(remaining, 0)))
+	}
+}
+
+// parseRateLimitReset parses X-RateLimit-Reset, which is either a Unix timestamp
+// or a number of seconds from now.
+func parseRateLimitReset(v string, now time.Time) (time.Time, bool) {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+
+	// Values that are too large to be a delay are timestamps.
+	if n > 1_000_000_000 {
+		return time.Unix(n, 0), true
+	}
+	return now.Add(time.Duration(n) * time.Second), true
+}
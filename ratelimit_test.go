@@ -0,0 +1,134 @@
+package leadsdb
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(rps float64, burst int) (*rateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := newRateLimiter(rps, burst)
+	l.now = clock.now
+	l.last = clock.t
+	return l, clock
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	l, clock := newTestLimiter(10, 2)
+
+	steps := []struct {
+		advance time.Duration
+		want    time.Duration
+	}{
+		{0, 0},
+		{0, 0},
+		{0, 100 * time.Millisecond},
+		{0, 200 * time.Millisecond},
+		// Paying back the two borrowed tokens and refilling one.
+		{300 * time.Millisecond, 0},
+		// The bucket never holds more than burst tokens.
+		{time.Hour, 0},
+		{0, 0},
+		{0, 100 * time.Millisecond},
+	}
+
+	for i, s := range steps {
+		clock.advance(s.advance)
+		if got := l.reserve(); got != s.want {
+			t.Fatalf("step %d: got delay %v, want %v", i, got, s.want)
+		}
+	}
+}
+
+func TestRateLimiterObserve(t *testing.T) {
+	header := func(kv ...string) http.Header {
+		h := http.Header{}
+		for i := 0; i < len(kv); i += 2 {
+			h.Set(kv[i], kv[i+1])
+		}
+		return h
+	}
+
+	tests := []struct {
+		name   string
+		status int
+		header func(now time.Time) http.Header
+		// want are the delays of the next reservations.
+		want []time.Duration
+	}{
+		{"no headers", http.StatusOK, func(time.Time) http.Header { return nil },
+			[]time.Duration{0, 0, 0}},
+		{"remaining lowers tokens", http.StatusOK, func(time.Time) http.Header {
+			return header("X-RateLimit-Remaining", "1")
+		}, []time.Duration{0, 100 * time.Millisecond}},
+		{"exhausted until reset delay", http.StatusOK, func(time.Time) http.Header {
+			return header("X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "5")
+		}, []time.Duration{5 * time.Second, 5 * time.Second}},
+		{"exhausted until reset timestamp", http.StatusOK, func(now time.Time) http.Header {
+			return header("X-RateLimit-Remaining", "0", "X-RateLimit-Reset", strconv.FormatInt(now.Add(30*time.Second).Unix(), 10))
+		}, []time.Duration{30 * time.Second}},
+		{"reset ignored while requests remain", http.StatusOK, func(time.Time) http.Header {
+			return header("X-RateLimit-Remaining", "3", "X-RateLimit-Reset", "5")
+		}, []time.Duration{0, 0, 0}},
+		{"retry after on 429", http.StatusTooManyRequests, func(time.Time) http.Header {
+			return header("Retry-After", "3")
+		}, []time.Duration{3 * time.Second, 3 * time.Second}},
+		{"retry after on 503", http.StatusServiceUnavailable, func(time.Time) http.Header {
+			return header("Retry-After", "2")
+		}, []time.Duration{2 * time.Second}},
+		{"retry after ignored on 200", http.StatusOK, func(time.Time) http.Header {
+			return header("Retry-After", "3")
+		}, []time.Duration{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(10, 5)
+			l.observe(&http.Response{StatusCode: tt.status, Header: tt.header(clock.t)})
+
+			for i, want := range tt.want {
+				if got := l.reserve(); got != want {
+					t.Fatalf("reservation %d: got delay %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterPauseEnds(t *testing.T) {
+	l, clock := newTestLimiter(10, 5)
+	l.observe(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"3"}}})
+
+	clock.advance(3 * time.Second)
+	if got := l.reserve(); got != 0 {
+		t.Fatalf("got delay %v after the pause, want 0", got)
+	}
+}
+
+func TestRateLimiterReturnsTokenOnCancel(t *testing.T) {
+	l, _ := newTestLimiter(1, 1)
+	if got := l.reserve(); got != 0 {
+		t.Fatalf("got delay %v for the first request, want 0", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.wait(ctx); err != context.Canceled {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if l.tokens != 0 {
+		t.Fatalf("got %v tokens after cancelling, want the token returned", l.tokens)
+	}
+	if got := l.reserve(); got != time.Second {
+		t.Fatalf("got delay %v, want 1s as if the cancelled request was never made", got)
+	}
+}
@@ -0,0 +1,82 @@
+package leadsdb
+
+import (
+	"crypto/rand"
+	"net/http"
+	"time"
+)
+
+// CreateOption configures a create call: Create, CreateNote or BulkCreate.
+type CreateOption func(*requestConfig)
+
+// UpdateOption configures an Update call.
+type UpdateOption func(*requestConfig)
+
+type requestConfig struct {
+	idempotencyKey    string
+	ifMatch           string
+	ifUnmodifiedSince time.Time
+}
+
+// IdempotencyKey sets the Idempotency-Key sent with a create request.
+//
+// The server performs a request once per key and answers repeats with the
+// original response, so a create retried after a timeout does not create a
+// duplicate. When no key is given, a random key is generated for each call and
+// reused by its retries; pass your own key to make retries across separate calls,
+// for example after a restart, safe as well.
+func IdempotencyKey(key string) CreateOption {
+	return func(cfg *requestConfig) {
+		cfg.idempotencyKey = key
+	}
+}
+
+// IfMatch makes an update succeed only if the lead's ETag still equals etag,
+// typically Lead.ETag from a previous read. Otherwise the update fails with an
+// error matching ErrConflict and the lead is left unchanged.
+func IfMatch(etag string) UpdateOption {
+	return func(cfg *requestConfig) {
+		cfg.ifMatch = etag
+	}
+}
+
+// IfUnmodifiedSince makes an update succeed only if the lead was not modified
+// after t, typically Lead.UpdatedAt from a previous read. Otherwise the update
+// fails with an error matching ErrConflict and the lead is left unchanged.
+//
+// The precision is one second; prefer IfMatch when the ETag is known.
+func IfUnmodifiedSince(t UnixTime) UpdateOption {
+	return func(cfg *requestConfig) {
+		cfg.ifUnmodifiedSince = t.Time
+	}
+}
+
+// requestOptions converts per-operation options for do.
+func requestOptions[O ~func(*requestConfig)](opts []O) []func(*requestConfig) {
+	fns := make([]func(*requestConfig), len(opts))
+	for i, opt := range opts {
+		fns[i] = opt
+	}
+	return fns
+}
+
+// header returns the request headers for a call with the given method.
+func (cfg *requestConfig) header(method string) http.Header {
+	header := http.Header{}
+
+	if method == http.MethodPost {
+		key := cfg.idempotencyKey
+		if key == "" {
+			key = rand.Text()
+		}
+		header.Set("Idempotency-Key", key)
+	}
+	if cfg.ifMatch != "" {
+		header.Set("If-Match", cfg.ifMatch)
+	}
+	if !cfg.ifUnmodifiedSince.IsZero() {
+		header.Set("If-Unmodified-Since", cfg.ifUnmodifiedSince.UTC().Format(http.TimeFormat))
+	}
+
+	return header
+}
@@ -0,0 +1,119 @@
+package leadsdb
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides whether a failed request is retried and how long to wait
+// before the next attempt. The number of attempts is limited by WithMaxRetries.
+type RetryPolicy interface {
+	// ShouldRetry reports whether a request that failed should be sent again.
+	// statusCode is 0 when err is set, which means the request failed before a
+	// response was received.
+	ShouldRetry(method string, statusCode int, err error) bool
+	// Delay returns how long to wait before the next attempt. attempt is 0 for
+	// the first retry, and retryAfter is the wait requested by the server, or 0.
+	Delay(attempt int, retryAfter time.Duration) time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy used when none is configured.
+//
+// It retries network errors, 429 and 5xx gateway responses regardless of the
+// method, and waits for the server's Retry-After or DefaultBaseDelay doubled on
+// every attempt, plus up to 500ms of random jitter.
+type DefaultRetryPolicy struct{}
+
+// ShouldRetry implements RetryPolicy.
+func (DefaultRetryPolicy) ShouldRetry(method string, statusCode int, err error) bool {
+	return err != nil || retryableStatus(statusCode)
+}
+
+// retryableStatus reports whether statusCode indicates a temporary failure.
+func retryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Delay implements RetryPolicy.
+func (DefaultRetryPolicy) Delay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = DefaultBaseDelay << attempt
+	}
+
+	return delay + time.Duration(rand.Int64N(int64(maxJitter)))
+}
+
+// WithRetryPolicy sets the policy deciding which failed requests are retried
+// and how long to wait between attempts. A nil policy restores DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		if p == nil {
+			p = DefaultRetryPolicy{}
+		}
+		c.retryPolicy = p
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP
+// date, which is relative to now. A date in the past yields a zero delay.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
+
+// retryWait logs the upcoming retry of a request that failed with err and
+// waits before it. The wait is capped by the deadline of ctx: when the delay
+// would outlast it, retryWait waits for ctx to end instead of retrying. It
+// returns ctx.Err() when ctx ends before the retry can be sent.
+func (c *Client) retryWait(ctx context.Context, r *Request, attrs []slog.Attr, attempt int, retryAfter time.Duration, err error) error {
+	delay := c.retryPolicy.Delay(attempt, retryAfter)
+	attrs = append(attrs, slog.Duration("delay", delay))
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "leadsdb: retry delay capped by context deadline", attrs...)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	c.inst.Retry(ctx, r.Method, requestPath(r.Path), attempt+1, delay)
+	c.logger.LogAttrs(ctx, slog.LevelWarn, "leadsdb: retrying request", append(attrs, errorAttrs(err)...)...)
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+	}
+	return ctx.Err()
+}
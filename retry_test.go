@@ -0,0 +1,122 @@
+package leadsdb_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+// fixedDelay retries like DefaultRetryPolicy, always waiting d.
+type fixedDelay struct {
+	leadsdb.DefaultRetryPolicy
+	d time.Duration
+}
+
+func (p fixedDelay) Delay(int, time.Duration) time.Duration { return p.d }
+
+func TestRetryWaitIsCappedByDeadline(t *testing.T) {
+	t.Run("delay within deadline", func(t *testing.T) {
+		srv := leadsdbtest.NewServer()
+		defer srv.Close()
+
+		lead := srv.AddLead(leadsdb.Lead{Name: "Acme", Source: "test"})
+		client := srv.Client(leadsdb.WithRetryPolicy(fixedDelay{d: 20 * time.Millisecond}))
+		srv.FailNext(http.StatusServiceUnavailable, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if _, err := client.Get(ctx, lead.ID); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got := len(srv.Requests()); got != 2 {
+			t.Fatalf("server received %d requests, want 2", got)
+		}
+	})
+
+	t.Run("delay past deadline", func(t *testing.T) {
+		srv := leadsdbtest.NewServer()
+		defer srv.Close()
+
+		lead := srv.AddLead(leadsdb.Lead{Name: "Acme", Source: "test"})
+		client := srv.Client(leadsdb.WithRetryPolicy(fixedDelay{d: time.Hour}))
+		srv.FailNext(http.StatusServiceUnavailable, 1)
+
+		const timeout = 100 * time.Millisecond
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		start := time.Now()
+		_, err := client.Get(ctx, lead.ID)
+		elapsed := time.Since(start)
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want an error matching context.DeadlineExceeded", err)
+		}
+		var apiErr *leadsdb.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("got %v, want the 503 APIError as well", err)
+		}
+		if elapsed < timeout || elapsed > 10*timeout {
+			t.Fatalf("returned after %v, want to wait until the %v deadline", elapsed, timeout)
+		}
+		if got := len(srv.Requests()); got != 1 {
+			t.Fatalf("server received %d requests, want 1", got)
+		}
+	})
+}
+
+// idempotentOnly retries only GET requests and records the delays the
+// server asked for.
+type idempotentOnly struct {
+	mu          sync.Mutex
+	retryAfters []time.Duration
+}
+
+func (p *idempotentOnly) ShouldRetry(method string, statusCode int, err error) bool {
+	return method == http.MethodGet
+}
+
+func (p *idempotentOnly) Delay(attempt int, retryAfter time.Duration) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retryAfters = append(p.retryAfters, retryAfter)
+	return 0
+}
+
+func TestCustomRetryPolicy(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	policy := &idempotentOnly{}
+	client := srv.Client(leadsdb.WithRetryPolicy(policy))
+	ctx := context.Background()
+
+	srv.FailNext(http.StatusServiceUnavailable, 1)
+	_, err := client.Create(ctx, &leadsdb.Lead{Name: "Acme", Source: "test"})
+	var apiErr *leadsdb.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("got %v, want the 503 without a retry", err)
+	}
+	if got := len(srv.Requests()); got != 1 {
+		t.Fatalf("server received %d requests, want 1", got)
+	}
+
+	lead := srv.AddLead(leadsdb.Lead{Name: "Acme", Source: "test"})
+	srv.InjectFault(leadsdbtest.Fault{Status: http.StatusServiceUnavailable, RetryAfter: "7"})
+	if _, err := client.Get(ctx, lead.ID); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := len(srv.Requests()); got != 3 {
+		t.Fatalf("server received %d requests, want 3", got)
+	}
+	if len(policy.retryAfters) != 1 || policy.retryAfters[0] != 7*time.Second {
+		t.Fatalf("Delay got retryAfter %v, want [7s]", policy.retryAfters)
+	}
+}
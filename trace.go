@@ -0,0 +1,33 @@
+package leadsdb
+
+import (
+	"context"
+	"regexp"
+)
+
+type traceContextKey struct{}
+
+type traceContext struct {
+	parent string
+	state  string
+}
+
+// traceparentPattern matches a version 00 W3C traceparent header.
+var traceparentPattern = regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// ContextWithTrace returns a copy of ctx carrying a W3C trace context. Requests
+// made with the returned context send traceparent, and tracestate when it is
+// not empty, so that the API's traces join the caller's.
+// An invalid traceparent is ignored and ctx is returned unchanged.
+func ContextWithTrace(ctx context.Context, traceparent, tracestate string) context.Context {
+	if !traceparentPattern.MatchString(traceparent) {
+		return ctx
+	}
+	return context.WithValue(ctx, traceContextKey{}, traceContext{parent: traceparent, state: tracestate})
+}
+
+// TraceFromContext returns the trace context stored by ContextWithTrace.
+func TraceFromContext(ctx context.Context) (traceparent, tracestate string, ok bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(traceContext)
+	return tc.parent, tc.state, ok
+}
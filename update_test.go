@@ -0,0 +1,106 @@
+package leadsdb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestUpdateFuncRetriesOnConflict(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	stored := srv.AddLead(leadsdb.Lead{Name: "Acme", Source: "test", ReviewCount: leadsdb.Ptr(1)})
+	client := srv.Client()
+	ctx := context.Background()
+
+	calls := 0
+	lead, err := client.UpdateFunc(ctx, stored.ID, func(lead *leadsdb.Lead) (*leadsdb.UpdateLeadInput, error) {
+		calls++
+		if calls == 1 {
+			// Another writer changes the lead between our read and write.
+			if _, err := client.Update(ctx, lead.ID, &leadsdb.UpdateLeadInput{ReviewCount: leadsdb.Ptr(*lead.ReviewCount + 10)}); err != nil {
+				return nil, err
+			}
+		}
+		return &leadsdb.UpdateLeadInput{ReviewCount: leadsdb.Ptr(*lead.ReviewCount + 1)}, nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateFunc: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("fn called %d times, want 2", calls)
+	}
+	if got := *lead.ReviewCount; got != 12 {
+		t.Errorf("review count = %d, want 12", got)
+	}
+}
+
+func TestUpdateFuncRequiresPrecondition(t *testing.T) {
+	var patches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			patches++
+		}
+		// No ETag header and no updated_at.
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"lead_1","name":"Acme","source":"test"}`)
+	}))
+	defer srv.Close()
+
+	client := leadsdb.New("key", leadsdb.WithBaseURL(srv.URL))
+
+	called := false
+	_, err := client.UpdateFunc(context.Background(), "lead_1", func(*leadsdb.Lead) (*leadsdb.UpdateLeadInput, error) {
+		called = true
+		return &leadsdb.UpdateLeadInput{City: leadsdb.Ptr("Berlin")}, nil
+	})
+	if err == nil {
+		t.Fatal("UpdateFunc succeeded without a precondition")
+	}
+	if errors.Is(err, leadsdb.ErrConflict) {
+		t.Errorf("got %v, want an error other than ErrConflict", err)
+	}
+	if called || patches != 0 {
+		t.Errorf("fn called: %v, PATCH requests: %d; want neither", called, patches)
+	}
+}
+
+func TestLeadsCarryETag(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	client := srv.Client()
+	ctx := context.Background()
+
+	created, err := client.Create(ctx, &leadsdb.Lead{Name: "Acme", Source: "test"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, err := client.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if created.ETag == "" || got.ETag != created.ETag {
+		t.Fatalf("Create returned ETag %q and Get %q, want the same non-empty ETag", created.ETag, got.ETag)
+	}
+
+	updated, err := client.Update(ctx, created.ID, &leadsdb.UpdateLeadInput{City: leadsdb.Ptr("Berlin")}, leadsdb.IfMatch(got.ETag))
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.ETag == "" || updated.ETag == got.ETag {
+		t.Fatalf("Update returned ETag %q, want a new one", updated.ETag)
+	}
+
+	_, err = client.Update(ctx, created.ID, &leadsdb.UpdateLeadInput{City: leadsdb.Ptr("Paris")}, leadsdb.IfMatch(got.ETag))
+	if !errors.Is(err, leadsdb.ErrConflict) {
+		t.Fatalf("got %v for a stale ETag, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,397 @@
+package leadsdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// upsertLookupSize is the number of source IDs looked up per list request in BulkUpsert.
+const upsertLookupSize = 50
+
+// UpsertStatus describes what an upsert did to a lead.
+type UpsertStatus string
+
+const (
+	// UpsertInserted means no lead with the same Source and SourceID existed and one was created.
+	UpsertInserted UpsertStatus = "inserted"
+	// UpsertUpdated means an existing lead was updated.
+	UpsertUpdated UpsertStatus = "updated"
+	// UpsertUnchanged means an existing lead already had the given values.
+	UpsertUnchanged UpsertStatus = "unchanged"
+	// UpsertFailed means the lead could not be upserted. Only used by BulkUpsert.
+	UpsertFailed UpsertStatus = "failed"
+)
+
+// UpsertResult contains the result of upserting a single lead.
+type UpsertResult struct {
+	// Lead is the stored lead. For leads inserted by BulkUpsert only ID and CreatedAt
+	// are filled in by the server; the remaining fields are copied from the input.
+	// For leads updated by BulkUpsert the changes are applied to the stored lead
+	// locally, and only UpdatedAt comes from the server.
+	Lead   *Lead
+	Status UpsertStatus
+	// Err is set when Status is UpsertFailed.
+	Err error
+}
+
+// BulkUpsertResult contains the result of a bulk upsert operation.
+type BulkUpsertResult struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
+	Failed    int
+	// Results holds one entry per input lead, in input order.
+	Results []UpsertResult
+}
+
+// Upsert creates lead, or updates the existing lead with exactly the same
+// Source and SourceID. The comparison is case-sensitive.
+//
+// When a lead exists, only the fields set on lead are applied: empty strings,
+// nil pointers and empty slices leave the stored values untouched, and
+// attributes are merged by name. No request is made when nothing changed.
+func (c *Client) Upsert(ctx context.Context, lead *Lead) (*UpsertResult, error) {
+	if msg := validateUpsert(lead); msg != "" {
+		return nil, errors.New("leadsdb: " + msg)
+	}
+
+	found, err := c.lookupBySourceID(ctx, []*Lead{lead})
+	if err != nil {
+		return nil, err
+	}
+
+	existing, ok := found[sourceKey{lead.Source, lead.SourceID}]
+	if !ok {
+		created, err := c.Create(ctx, lead)
+		if err != nil {
+			return nil, err
+		}
+		return &UpsertResult{Lead: created, Status: UpsertInserted}, nil
+	}
+
+	input := upsertInput(existing, lead)
+	if input == nil {
+		return &UpsertResult{Lead: existing, Status: UpsertUnchanged}, nil
+	}
+
+	updated, err := c.Update(ctx, existing.ID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UpsertResult{Lead: updated, Status: UpsertUpdated}, nil
+}
+
+// BulkUpsert upserts many leads, looking up existing leads in batches,
+// updating them with BulkUpdate and creating new ones with BulkCreateAll.
+// Leads follow the same rules as Upsert.
+//
+// Per-lead failures are reported in the result; the returned error is only
+// set for invalid input or when looking up existing leads fails.
+func (c *Client) BulkUpsert(ctx context.Context, leads []*Lead) (*BulkUpsertResult, error) {
+	if len(leads) == 0 {
+		return nil, errors.New("leadsdb: leads is required")
+	}
+	for i, lead := range leads {
+		if msg := validateUpsert(lead); msg != "" {
+			return nil, fmt.Errorf("leadsdb: lead at index %d: %s", i, msg)
+		}
+	}
+
+	existing, err := c.lookupBySourceID(ctx, leads)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkUpsertResult{Results: make([]UpsertResult, len(leads))}
+	seen := make(map[sourceKey]int, len(leads))
+
+	var inserts, updates []int
+	var patches []LeadPatch
+	for i, lead := range leads {
+		key := sourceKey{lead.Source, lead.SourceID}
+		if first, ok := seen[key]; ok {
+			result.Results[i] = UpsertResult{
+				Status: UpsertFailed,
+				Err:    fmt.Errorf("leadsdb: duplicate of lead at index %d", first),
+			}
+			continue
+		}
+		seen[key] = i
+
+		current, ok := existing[key]
+		if !ok {
+			inserts = append(inserts, i)
+			continue
+		}
+
+		input := upsertInput(current, lead)
+		if input == nil {
+			result.Results[i] = UpsertResult{Lead: current, Status: UpsertUnchanged}
+			continue
+		}
+
+		updates = append(updates, i)
+		patches = append(patches, LeadPatch{ID: current.ID, Input: input})
+	}
+
+	for start := 0; start < len(patches); start += maxBatchSize {
+		end := min(start+maxBatchSize, len(patches))
+
+		updated, err := c.BulkUpdate(ctx, patches[start:end])
+		if err != nil {
+			for _, i := range updates[start:end] {
+				result.Results[i] = UpsertResult{Status: UpsertFailed, Err: err}
+			}
+			continue
+		}
+
+		for _, r := range updated.Updated {
+			i := updates[start+r.Index]
+			lead := mergeUpsert(existing[sourceKey{leads[i].Source, leads[i].SourceID}], leads[i])
+			lead.UpdatedAt = r.UpdatedAt
+			result.Results[i] = UpsertResult{Lead: lead, Status: UpsertUpdated}
+		}
+		for _, e := range updated.Errors {
+			e.Index = updates[start+e.Index]
+			result.Results[e.Index] = UpsertResult{Status: UpsertFailed, Err: &e}
+		}
+	}
+
+	if len(inserts) > 0 {
+		batch := make([]*Lead, len(inserts))
+		for j, i := range inserts {
+			batch[j] = leads[i]
+		}
+
+		created, err := c.BulkCreateAll(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range created.Created {
+			lead := *batch[r.Index]
+			lead.ID = r.ID
+			lead.CreatedAt = r.CreatedAt
+			lead.UpdatedAt = r.CreatedAt
+			result.Results[inserts[r.Index]] = UpsertResult{Lead: &lead, Status: UpsertInserted}
+		}
+		for _, e := range created.Errors {
+			e.Index = inserts[e.Index]
+			result.Results[e.Index] = UpsertResult{Status: UpsertFailed, Err: &e}
+		}
+	}
+
+	for _, r := range result.Results {
+		switch r.Status {
+		case UpsertInserted:
+			result.Inserted++
+		case UpsertUpdated:
+			result.Updated++
+		case UpsertUnchanged:
+			result.Unchanged++
+		default:
+			result.Failed++
+		}
+	}
+
+	return result, nil
+}
+
+type sourceKey struct {
+	source   string
+	sourceID string
+}
+
+// lookupBySourceID returns the stored leads matching the Source and SourceID of leads.
+// The API compares text case-insensitively, so returned leads are checked
+// against the exact values and leads differing only in case are not confused.
+func (c *Client) lookupBySourceID(ctx context.Context, leads []*Lead) (map[sourceKey]*Lead, error) {
+	bySource := make(map[string][]string)
+	for _, lead := range leads {
+		if !slices.Contains(bySource[lead.Source], lead.SourceID) {
+			bySource[lead.Source] = append(bySource[lead.Source], lead.SourceID)
+		}
+	}
+
+	found := make(map[sourceKey]*Lead, len(leads))
+	for source, ids := range bySource {
+		for chunk := range slices.Chunk(ids, upsertLookupSize) {
+			conds := make([]Condition, len(chunk))
+			for i, id := range chunk {
+				conds[i] = SourceID().Eq(id)
+			}
+
+			for lead, err := range c.Iterator(ctx, Source().Eq(source), AnyOf(conds...), Limit(maxBatchSize)) {
+				if err != nil {
+					return nil, err
+				}
+				// Only trust leads that really carry a requested key, so a
+				// server applying the filters loosely cannot cause a wrong match.
+				if lead.Source != source || !slices.Contains(chunk, lead.SourceID) {
+					continue
+				}
+				found[sourceKey{lead.Source, lead.SourceID}] = lead
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// validateUpsert returns a description of what is missing from lead, or an empty string.
+func validateUpsert(lead *Lead) string {
+	switch {
+	case lead == nil:
+		return "lead is required"
+	case lead.Name == "":
+		return "name is required"
+	case lead.Source == "":
+		return "source is required"
+	case lead.SourceID == "":
+		return "source_id is required"
+	default:
+		return ""
+	}
+}
+
+// upsertInput returns the changes needed to apply the set fields of lead to
+// existing, or nil when there are none.
+func upsertInput(existing, lead *Lead) *UpdateLeadInput {
+	var input UpdateLeadInput
+	changed := false
+
+	setString := func(dst **string, old, new string) {
+		if new != "" && new != old {
+			*dst = Ptr(new)
+			changed = true
+		}
+	}
+
+	setString(&input.Name, existing.Name, lead.Name)
+	setString(&input.Description, existing.Description, lead.Description)
+	setString(&input.Address, existing.Address, lead.Address)
+	setString(&input.City, existing.City, lead.City)
+	setString(&input.State, existing.State, lead.State)
+	setString(&input.Country, existing.Country, lead.Country)
+	setString(&input.PostalCode, existing.PostalCode, lead.PostalCode)
+	setString(&input.Phone, existing.Phone, lead.Phone)
+	setString(&input.Email, existing.Email, lead.Email)
+	setString(&input.Website, existing.Website, lead.Website)
+	setString(&input.Category, existing.Category, lead.Category)
+	setString(&input.LogoURL, existing.LogoURL, lead.LogoURL)
+
+	if lead.Coordinates != nil && (existing.Coordinates == nil || *existing.Coordinates != *lead.Coordinates) {
+		input.Coordinates = lead.Coordinates
+		changed = true
+	}
+	if lead.Rating != nil && (existing.Rating == nil || *existing.Rating != *lead.Rating) {
+		input.Rating = lead.Rating
+		changed = true
+	}
+	if lead.ReviewCount != nil && (existing.ReviewCount == nil || *existing.ReviewCount != *lead.ReviewCount) {
+		input.ReviewCount = lead.ReviewCount
+		changed = true
+	}
+	if len(lead.Tags) > 0 && !slices.Equal(existing.Tags, lead.Tags) {
+		input.Tags = lead.Tags
+		changed = true
+	}
+	if attrs := changedAttributes(existing.Attributes, lead.Attributes); len(attrs) > 0 {
+		input.SetAttributes = attrs
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return &input
+}
+
+// mergeUpsert returns a copy of existing with the set fields of lead applied,
+// as the API stores them after updating with upsertInput(existing, lead).
+func mergeUpsert(existing, lead *Lead) *Lead {
+	merged := *existing
+	merged.ETag = ""
+
+	setString := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+
+	setString(&merged.Name, lead.Name)
+	setString(&merged.Description, lead.Description)
+	setString(&merged.Address, lead.Address)
+	setString(&merged.City, lead.City)
+	setString(&merged.State, lead.State)
+	setString(&merged.Country, lead.Country)
+	setString(&merged.PostalCode, lead.PostalCode)
+	setString(&merged.Phone, lead.Phone)
+	setString(&merged.Email, lead.Email)
+	setString(&merged.Website, lead.Website)
+	setString(&merged.Category, lead.Category)
+	setString(&merged.LogoURL, lead.LogoURL)
+
+	if lead.Coordinates != nil {
+		merged.Coordinates = Ptr(*lead.Coordinates)
+	}
+	if lead.Rating != nil {
+		merged.Rating = Ptr(*lead.Rating)
+	}
+	if lead.ReviewCount != nil {
+		merged.ReviewCount = Ptr(*lead.ReviewCount)
+	}
+	if len(lead.Tags) > 0 {
+		merged.Tags = slices.Clone(lead.Tags)
+	}
+
+	merged.Attributes = slices.Clone(existing.Attributes)
+	for _, attr := range lead.Attributes {
+		i := slices.IndexFunc(merged.Attributes, func(a Attribute) bool { return a.Name == attr.Name })
+		if i < 0 {
+			merged.Attributes = append(merged.Attributes, attr)
+			continue
+		}
+		merged.Attributes[i] = attr
+	}
+
+	return &merged
+}
+
+// changedAttributes returns the attributes of attrs that are missing from
+// existing or differ from the attribute with the same name.
+func changedAttributes(existing, attrs []Attribute) []Attribute {
+	var changed []Attribute
+
+	for _, attr := range attrs {
+		i := slices.IndexFunc(existing, func(a Attribute) bool { return a.Name == attr.Name })
+		if i < 0 || !attributeEqual(existing[i], attr) {
+			changed = append(changed, attr)
+		}
+	}
+
+	return changed
+}
+
+// attributeEqual compares attributes by their JSON encoding, so that values
+// decoded from the API compare equal to the typed values they were created from.
+func attributeEqual(a, b Attribute) bool {
+	if a.Name != b.Name || a.Type != b.Type {
+		return false
+	}
+
+	av, err := json.Marshal(a.Value)
+	if err != nil {
+		return false
+	}
+	bv, err := json.Marshal(b.Value)
+	if err != nil {
+		return false
+	}
+
+	return string(av) == string(bv)
+}
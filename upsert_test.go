@@ -0,0 +1,173 @@
+package leadsdb_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gosom/go-leadsdb"
+	"github.com/gosom/go-leadsdb/leadsdbtest"
+)
+
+func TestUpsertMatchesSourceIDExactly(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		upsert func(*leadsdb.Client, *leadsdb.Lead) (leadsdb.UpsertStatus, error)
+	}{
+		{"Upsert", func(c *leadsdb.Client, lead *leadsdb.Lead) (leadsdb.UpsertStatus, error) {
+			result, err := c.Upsert(ctx, lead)
+			if err != nil {
+				return "", err
+			}
+			return result.Status, nil
+		}},
+		{"BulkUpsert", func(c *leadsdb.Client, lead *leadsdb.Lead) (leadsdb.UpsertStatus, error) {
+			result, err := c.BulkUpsert(ctx, []*leadsdb.Lead{lead})
+			if err != nil {
+				return "", err
+			}
+			return result.Results[0].Status, result.Results[0].Err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := leadsdbtest.NewServer()
+			defer srv.Close()
+
+			srv.AddLead(leadsdb.Lead{Name: "Lower", Source: "maps", SourceID: "ChIJabc", City: "Berlin"})
+			client := srv.Client()
+
+			status, err := tt.upsert(client, &leadsdb.Lead{Name: "Upper", Source: "maps", SourceID: "ChIJABC", City: "Munich"})
+			if err != nil {
+				t.Fatalf("upsert: %v", err)
+			}
+			if status != leadsdb.UpsertInserted {
+				t.Fatalf("got status %s, want %s", status, leadsdb.UpsertInserted)
+			}
+
+			leads := srv.Leads()
+			if len(leads) != 2 {
+				t.Fatalf("got %d leads, want 2", len(leads))
+			}
+			if leads[0].City != "Berlin" {
+				t.Fatalf("existing lead was changed: city %q", leads[0].City)
+			}
+
+			status, err = tt.upsert(client, &leadsdb.Lead{Name: "Lower", Source: "maps", SourceID: "ChIJabc", City: "Hamburg"})
+			if err != nil {
+				t.Fatalf("upsert: %v", err)
+			}
+			if status != leadsdb.UpsertUpdated {
+				t.Fatalf("got status %s, want %s", status, leadsdb.UpsertUpdated)
+			}
+			if got := srv.Leads()[0].City; got != "Hamburg" {
+				t.Fatalf("got city %q, want Hamburg", got)
+			}
+		})
+	}
+}
+
+// dropSourceIDFilters removes source_id filters from list requests, like a
+// server that does not apply them.
+func dropSourceIDFilters(next leadsdb.Doer) leadsdb.Doer {
+	return leadsdb.DoerFunc(func(ctx context.Context, req *leadsdb.Request) (*leadsdb.Response, error) {
+		if path, query, ok := strings.Cut(req.Path, "?"); ok && req.Method == http.MethodGet {
+			params, err := url.ParseQuery(query)
+			if err != nil {
+				return nil, err
+			}
+			var kept []string
+			for _, f := range params["filter"] {
+				if !strings.Contains(f, ".source_id.") {
+					kept = append(kept, f)
+				}
+			}
+			params["filter"] = kept
+			req.Path = path + "?" + params.Encode()
+		}
+		return next.Do(ctx, req)
+	})
+}
+
+func TestUpsertChecksReturnedSourceID(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	srv.AddLead(leadsdb.Lead{Name: "Other", Source: "maps", SourceID: "a", City: "Berlin"})
+	client := srv.Client(leadsdb.WithMiddleware(dropSourceIDFilters))
+	ctx := context.Background()
+
+	result, err := client.Upsert(ctx, &leadsdb.Lead{Name: "New", Source: "maps", SourceID: "b", City: "Munich"})
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if result.Status != leadsdb.UpsertInserted {
+		t.Fatalf("Upsert: got status %s, want %s", result.Status, leadsdb.UpsertInserted)
+	}
+
+	bulk, err := client.BulkUpsert(ctx, []*leadsdb.Lead{{Name: "Newer", Source: "maps", SourceID: "c"}})
+	if err != nil {
+		t.Fatalf("BulkUpsert: %v", err)
+	}
+	if bulk.Inserted != 1 {
+		t.Fatalf("BulkUpsert: got %+v, want one insert", bulk)
+	}
+
+	if got := srv.Leads()[0]; got.Name != "Other" || got.City != "Berlin" {
+		t.Fatalf("unrelated lead was updated: %+v", got)
+	}
+}
+
+func TestBulkUpsertUpdatesInBatches(t *testing.T) {
+	srv := leadsdbtest.NewServer()
+	defer srv.Close()
+
+	const total = 150
+	leads := make([]*leadsdb.Lead, total)
+	for i := range total {
+		id := fmt.Sprintf("id-%d", i)
+		srv.AddLead(leadsdb.Lead{Name: "Lead", Source: "maps", SourceID: id, City: "Berlin",
+			Attributes: []leadsdb.Attribute{{Name: "tier", Value: "silver"}}})
+		leads[i] = &leadsdb.Lead{Name: "Lead", Source: "maps", SourceID: id, City: "Paris",
+			Attributes: []leadsdb.Attribute{{Name: "size", Value: 10.0}}}
+	}
+	client := srv.Client()
+
+	result, err := client.BulkUpsert(context.Background(), leads)
+	if err != nil {
+		t.Fatalf("BulkUpsert: %v", err)
+	}
+	if result.Updated != total {
+		t.Fatalf("got %+v, want %d updates", result, total)
+	}
+
+	for i, r := range result.Results {
+		if r.Lead.SourceID != leads[i].SourceID || r.Lead.City != "Paris" || len(r.Lead.Attributes) != 2 || r.Lead.UpdatedAt.IsZero() {
+			t.Fatalf("result %d: got %+v, want the merged lead %s", i, r.Lead, leads[i].SourceID)
+		}
+	}
+
+	var batches int
+	for _, req := range srv.Requests() {
+		switch {
+		case req.Method == http.MethodPatch && req.Path == "/leads/batch":
+			batches++
+		case req.Method == http.MethodPatch:
+			t.Fatalf("unexpected single update %s", req.Path)
+		}
+	}
+	if batches != 2 {
+		t.Fatalf("sent %d bulk updates, want 2", batches)
+	}
+	for _, lead := range srv.Leads() {
+		if lead.City != "Paris" {
+			t.Fatalf("lead %s not updated: city %q", lead.SourceID, lead.City)
+		}
+	}
+}